@@ -12,6 +12,14 @@ func newUpdate(node *Tree, value string) *Action {
 	return &Action{Type: Update, Node: node, Value: value}
 }
 
+func newUpdateAttr(node *Tree, key, value string) *Action {
+	return &Action{Type: UpdateAttr, Node: node, Key: key, Value: value}
+}
+
+func newDeleteAttr(node *Tree, key string) *Action {
+	return &Action{Type: DeleteAttr, Node: node, Key: key}
+}
+
 func newMove(node, parent *Tree, pos int) *Action {
 	return &Action{Type: Move, Node: node, Parent: parent, Pos: pos}
 }
@@ -109,7 +117,7 @@ func (g *actionGenerator) Generate() []*Action {
 			// Insert phase
 			// insert new node if there is no such node in dst tree side of new mapping
 			k := g.findPos(x)
-			w = &Tree{id: g.newID(), parent: z}
+			w = &Tree{id: g.newID(), parent: z, Unordered: x.Unordered}
 			// use real node in the action
 			ins := newInsert(x, g.origSrcTrees[z.id], k)
 			actions = append(actions, ins)
@@ -128,6 +136,9 @@ func (g *actionGenerator) Generate() []*Action {
 				// update the clone
 				w.Value = x.Value
 			}
+			if !w.EqualAttrs(x) {
+				actions = append(actions, g.updateAttrs(w, x)...)
+			}
 			// Move phase
 			v := w.parent
 			if z != v {
@@ -163,6 +174,27 @@ func (g *actionGenerator) Generate() []*Action {
 	return g.simplify(actions)
 }
 
+// updateAttrs returns actions for attributes of x that differ in w
+// followed by attributes missing in x and updates the clone w
+func (g *actionGenerator) updateAttrs(w, x *Tree) []*Action {
+	var actions []*Action
+	node := g.origSrcTrees[w.id]
+	for _, a := range x.Attrs {
+		if v, ok := w.GetAttr(a.Key); !ok || v != a.Value {
+			actions = append(actions, newUpdateAttr(node, a.Key, a.Value))
+		}
+	}
+	for _, a := range w.Attrs {
+		if _, ok := x.GetAttr(a.Key); !ok {
+			actions = append(actions, newDeleteAttr(node, a.Key))
+		}
+	}
+
+	// the clone shares attributes with the original node
+	w.Attrs = append([]Attr(nil), x.Attrs...)
+	return actions
+}
+
 // children of w and x are misaligned
 // when mapped children of w have different order in x
 //
@@ -179,6 +211,17 @@ func (g *actionGenerator) alignChildren(w, x *Tree) []*Action {
 		delete(g.dstInOrder, c)
 	}
 
+	// mapped children of sets are never out of order
+	if hasUnorderedChildren(w, x) {
+		for _, c := range x.Children {
+			if s, ok := g.newMappings.GetSrc(c); ok && s.parent == w {
+				g.srcInOrder[s] = true
+				g.dstInOrder[c] = true
+			}
+		}
+		return actions
+	}
+
 	// children of src node that are mappend and belong to a partner node in dst
 	s1 := make([]*Tree, 0)
 	for _, c := range w.Children {
@@ -178,6 +178,20 @@ func apply(t *Tree, actions []*Action) *Tree {
 			idToNode[nid] = n
 		case Update:
 			idToNode[nid].Value = a.Value
+		case UpdateAttr:
+			node := idToNode[nid]
+			// attributes are shared with the original tree
+			node.Attrs = append([]Attr(nil), node.Attrs...)
+			node.SetAttr(a.Key, a.Value)
+		case DeleteAttr:
+			node := idToNode[nid]
+			attrs := make([]Attr, 0, len(node.Attrs))
+			for _, attr := range node.Attrs {
+				if attr.Key != a.Key {
+					attrs = append(attrs, attr)
+				}
+			}
+			node.Attrs = attrs
 		case Move:
 			idToNode[nid].GetParent().removeChild(idToNode[nid])
 			idToNode[a.Parent.GetID()].addChild(a.Pos, idToNode[nid])
@@ -259,8 +273,9 @@ func treeString(t *Tree) string {
 }
 
 func nodeString(t *Tree) string {
+	s := t.Type
 	if t.Value != "" {
-		return fmt.Sprintf("%s[%s]", t.Type, t.Value)
+		s = fmt.Sprintf("%s[%s]", t.Type, t.Value)
 	}
-	return t.Type
+	return s + t.attrsString()
 }
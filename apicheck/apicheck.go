@@ -0,0 +1,514 @@
+package apicheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/dirdiff"
+	"github.com/smacker/gum/golang"
+)
+
+// Package is a version of a Go package
+type Package struct {
+	Fset *token.FileSet
+	// Files keyed by paths relative to the directory of the package
+	Files map[string]*ast.File
+}
+
+// ParseDir parses go files of the package in the directory, tests are skipped
+func ParseDir(dir string) (*Package, error) {
+	p := &Package{Fset: token.NewFileSet(), Files: make(map[string]*ast.File)}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(p.Fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse the file %s: %s", name, err)
+		}
+		p.Files[name] = f
+	}
+
+	return p, nil
+}
+
+// Change is a change of exported API
+type Change struct {
+	// Name of the declaration, methods and fields are prefixed with the type
+	Name string `json:"name"`
+	// Kind is one of func, method, type, field, const or var
+	Kind string `json:"kind"`
+	// Change is one of added, removed or changed
+	Change   string `json:"change"`
+	Breaking bool   `json:"breaking"`
+	// Message describes what was changed
+	Message string `json:"message,omitempty"`
+}
+
+func (c *Change) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Kind, c.Name, c.Change)
+	if c.Message != "" {
+		s += ": " + c.Message
+	}
+	return s
+}
+
+// Report contains changes of exported API between two versions of a package
+type Report struct {
+	Changes []*Change `json:"changes"`
+}
+
+// Breaking returns true if any change breaks compatibility
+func (r *Report) Breaking() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes changes grouped by compatibility
+func (r *Report) WriteText(w io.Writer) error {
+	for _, breaking := range []bool{true, false} {
+		for _, c := range r.Changes {
+			if c.Breaking != breaking {
+				continue
+			}
+			prefix := "compatible"
+			if breaking {
+				prefix = "breaking"
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s\n", prefix, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as json
+func (r *Report) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// Check compares exported declarations of two versions of a package
+//
+// Declarations are paired using mappings of the matcher and moves between files,
+// so a declaration moved to another file isn't reported as removed.
+// Declarations the matcher didn't pair are looked up by name.
+func Check(old, updated *Package) *Report {
+	oldVersion := newVersion(old)
+	newVersion := newVersion(updated)
+	res := dirdiff.Compare(oldVersion.trees, newVersion.trees)
+
+	partners := make(map[*gum.Tree]*gum.Tree)
+	for _, f := range res.Files {
+		for _, m := range f.Mappings {
+			partners[m[0]] = m[1]
+		}
+	}
+	for _, m := range res.Moves {
+		partners[m.Src] = m.Dst
+	}
+
+	// declarations mapped by the matcher are paired first,
+	// the rest is paired by name
+	pairs := make(map[*decl]*decl)
+	paired := make(map[*decl]bool)
+	for _, d := range oldVersion.decls {
+		if p := newVersion.mapped(d, partners); p != nil {
+			pairs[d] = p
+			paired[p] = true
+		}
+	}
+	for _, d := range oldVersion.decls {
+		if pairs[d] != nil {
+			continue
+		}
+		if p := newVersion.byName(d, paired); p != nil {
+			pairs[d] = p
+			paired[p] = true
+		}
+	}
+
+	c := &checker{old: oldVersion, new: newVersion}
+	for _, d := range oldVersion.decls {
+		p := pairs[d]
+		if p == nil {
+			c.add(d.name, d.kind, "removed", true, "")
+			continue
+		}
+		c.compare(d, p)
+	}
+	for _, d := range newVersion.decls {
+		if !paired[d] {
+			c.add(d.name, d.kind, "added", false, "")
+		}
+	}
+
+	return &Report{Changes: c.changes}
+}
+
+// version is a package converted to trees with exported declarations
+type version struct {
+	trees map[string]*gum.Tree
+	decls []*decl
+	info  *types.Info
+}
+
+// decl is an exported top-level declaration
+type decl struct {
+	name string
+	kind string
+	// FuncDecl or spec
+	tree *gum.Tree
+	// top-level declaration
+	top   *gum.Tree
+	ident *ast.Ident
+	// index of the name in ValueSpec
+	index int
+}
+
+func newVersion(p *Package) *version {
+	v := &version{trees: make(map[string]*gum.Tree)}
+
+	var paths []string
+	for path := range p.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var files []*ast.File
+	for _, path := range paths {
+		f := p.Files[path]
+		files = append(files, f)
+		t := golang.ToTree(f)
+		v.trees[path] = t
+		for _, top := range t.Children {
+			v.decls = append(v.decls, exportedDecls(top)...)
+		}
+	}
+	v.info = golang.TypeCheck(p.Fset, files)
+
+	return v
+}
+
+func exportedDecls(top *gum.Tree) []*decl {
+	var decls []*decl
+	switch n := top.Meta.(type) {
+	case *ast.FuncDecl:
+		if !n.Name.IsExported() {
+			break
+		}
+		if n.Recv == nil {
+			decls = append(decls, &decl{name: n.Name.Name, kind: "func", tree: top, top: top, ident: n.Name})
+			break
+		}
+		if recv := baseName(n.Recv.List[0].Type); ast.IsExported(recv) {
+			decls = append(decls, &decl{name: recv + "." + n.Name.Name, kind: "method", tree: top, top: top, ident: n.Name})
+		}
+	case *ast.GenDecl:
+		for _, c := range top.Children {
+			switch s := c.Meta.(type) {
+			case *ast.TypeSpec:
+				if s.Name.IsExported() {
+					decls = append(decls, &decl{name: s.Name.Name, kind: "type", tree: c, top: top, ident: s.Name})
+				}
+			case *ast.ValueSpec:
+				for i, name := range s.Names {
+					if name.IsExported() {
+						decls = append(decls, &decl{
+							name:  name.Name,
+							kind:  strings.ToLower(n.Tok.String()),
+							tree:  c,
+							top:   top,
+							ident: name,
+							index: i,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return decls
+}
+
+// mapped returns the declaration of the same name the node of d is mapped to
+func (v *version) mapped(d *decl, partners map[*gum.Tree]*gum.Tree) *decl {
+	p, ok := partners[d.tree]
+	if !ok {
+		return nil
+	}
+	for _, c := range v.decls {
+		if c.tree == p && c.name == d.name && c.kind == d.kind {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// byName returns the declaration of the same name that isn't paired yet
+func (v *version) byName(d *decl, paired map[*decl]bool) *decl {
+	for _, c := range v.decls {
+		if c.name == d.name && c.kind == d.kind && !paired[c] {
+			return c
+		}
+	}
+
+	return nil
+}
+
+type checker struct {
+	old, new *version
+	changes  []*Change
+}
+
+func (c *checker) add(name, kind, change string, breaking bool, message string) {
+	c.changes = append(c.changes, &Change{
+		Name:     name,
+		Kind:     kind,
+		Change:   change,
+		Breaking: breaking,
+		Message:  message,
+	})
+}
+
+func (c *checker) changed(name, kind string, breaking bool, format string, args ...interface{}) {
+	c.add(name, kind, "changed", breaking, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) compare(old, updated *decl) {
+	switch o := old.tree.Meta.(type) {
+	case *ast.FuncDecl:
+		n := updated.tree.Meta.(*ast.FuncDecl)
+		if o.Recv != nil && n.Recv != nil {
+			or, nr := types.ExprString(o.Recv.List[0].Type), types.ExprString(n.Recv.List[0].Type)
+			// methods of the value are in the method set of the pointer
+			if or != nr {
+				c.changed(old.name, old.kind, !strings.HasPrefix(or, "*"), "receiver %s → %s", or, nr)
+			}
+		}
+		if ot, nt := funcString(o.Type), funcString(n.Type); ot != nt {
+			c.changed(old.name, old.kind, true, "%s → %s", ot, nt)
+		}
+	case *ast.TypeSpec:
+		c.compareTypes(old.name, o, updated.tree.Meta.(*ast.TypeSpec))
+	case *ast.ValueSpec:
+		n := updated.tree.Meta.(*ast.ValueSpec)
+		if ot, nt := c.old.valueType(o, old), c.new.valueType(n, updated); ot != nt {
+			c.changed(old.name, old.kind, true, "type %s → %s", ot, nt)
+		}
+		if old.kind == "const" {
+			if ov, nv := c.old.constValue(o, old), c.new.constValue(n, updated); ov != nv {
+				c.changed(old.name, old.kind, true, "value %s → %s", ov, nv)
+			}
+		}
+	}
+}
+
+func (c *checker) compareTypes(name string, o, n *ast.TypeSpec) {
+	if o.Assign.IsValid() != n.Assign.IsValid() {
+		c.changed(name, "type", true, "alias %s → %s", typeSpecString(o), typeSpecString(n))
+		return
+	}
+	if ot, nt := fieldsString(o.TypeParams), fieldsString(n.TypeParams); ot != nt {
+		c.changed(name, "type", true, "type parameters [%s] → [%s]", ot, nt)
+	}
+
+	ost, ok1 := o.Type.(*ast.StructType)
+	nst, ok2 := n.Type.(*ast.StructType)
+	if ok1 && ok2 {
+		c.compareMembers(name, "field", structFields(ost), structFields(nst), false)
+		return
+	}
+	oi, ok1 := o.Type.(*ast.InterfaceType)
+	ni, ok2 := n.Type.(*ast.InterfaceType)
+	if ok1 && ok2 {
+		// any new method breaks implementations of the interface
+		c.compareMembers(name, "method", interfaceMethods(oi), interfaceMethods(ni), true)
+		return
+	}
+
+	if ot, nt := types.ExprString(o.Type), types.ExprString(n.Type); ot != nt {
+		c.changed(name, "type", true, "%s → %s", ot, nt)
+	}
+}
+
+// compareMembers compares fields or methods of types keyed by names
+func (c *checker) compareMembers(typeName, kind string, old, updated map[string]string, addBreaks bool) {
+	for _, name := range sortedKeys(old) {
+		n, ok := updated[name]
+		if !ok {
+			c.add(typeName+"."+name, kind, "removed", true, "")
+			continue
+		}
+		if old[name] != n {
+			c.changed(typeName+"."+name, kind, true, "%s → %s", old[name], n)
+		}
+	}
+	for _, name := range sortedKeys(updated) {
+		if _, ok := old[name]; !ok {
+			c.add(typeName+"."+name, kind, "added", addBreaks, "")
+		}
+	}
+}
+
+// valueType returns type of a const or var, types are resolved by the type checker if possible
+func (v *version) valueType(s *ast.ValueSpec, d *decl) string {
+	if s.Type != nil {
+		return types.ExprString(s.Type)
+	}
+	if obj := v.info.Defs[d.ident]; obj != nil && obj.Type() != types.Typ[types.Invalid] {
+		return types.TypeString(obj.Type(), func(p *types.Package) string { return p.Name() })
+	}
+	return ""
+}
+
+// constValue returns value of a constant, iota and expressions are evaluated by the type checker
+func (v *version) constValue(s *ast.ValueSpec, d *decl) string {
+	if obj, ok := v.info.Defs[d.ident].(*types.Const); ok && obj.Val() != nil {
+		return obj.Val().ExactString()
+	}
+	if d.index < len(s.Values) {
+		return types.ExprString(s.Values[d.index])
+	}
+	return ""
+}
+
+// structFields returns types of exported fields keyed by names
+func structFields(s *ast.StructType) map[string]string {
+	fields := make(map[string]string)
+	for _, f := range s.Fields.List {
+		t := types.ExprString(f.Type)
+		if len(f.Names) == 0 {
+			// embedded field
+			if name := baseName(f.Type); ast.IsExported(name) {
+				fields[name] = t
+			}
+			continue
+		}
+		for _, name := range f.Names {
+			if name.IsExported() {
+				fields[name.Name] = t
+			}
+		}
+	}
+	return fields
+}
+
+// interfaceMethods returns signatures of methods keyed by names,
+// embedded interfaces and type constraints are keyed by their text
+func interfaceMethods(s *ast.InterfaceType) map[string]string {
+	methods := make(map[string]string)
+	for _, f := range s.Methods.List {
+		if len(f.Names) == 0 {
+			t := types.ExprString(f.Type)
+			methods[t] = t
+			continue
+		}
+		ft, ok := f.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		for _, name := range f.Names {
+			methods[name.Name] = funcString(ft)
+		}
+	}
+	return methods
+}
+
+// funcString returns signature of the function without names of parameters
+func funcString(ft *ast.FuncType) string {
+	s := "func"
+	if ft.TypeParams != nil {
+		s += "[" + fieldsString(ft.TypeParams) + "]"
+	}
+	s += "(" + fieldsString(ft.Params) + ")"
+	if ft.Results != nil && len(ft.Results.List) > 0 {
+		results := fieldsString(ft.Results)
+		if ft.Results.NumFields() > 1 {
+			results = "(" + results + ")"
+		}
+		s += " " + results
+	}
+	return s
+}
+
+// fieldsString returns types of the fields repeated for each name
+func fieldsString(fl *ast.FieldList) string {
+	if fl == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, f := range fl.List {
+		t := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func typeSpecString(s *ast.TypeSpec) string {
+	if s.Assign.IsValid() {
+		return "= " + types.ExprString(s.Type)
+	}
+	return types.ExprString(s.Type)
+}
+
+// baseName returns name of the type without pointer, package and type arguments
+func baseName(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return baseName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return baseName(t.X)
+	case *ast.IndexListExpr:
+		return baseName(t.X)
+	case *ast.ParenExpr:
+		return baseName(t.X)
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,68 @@
+package apicheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	old, err := ParseDir("testdata/old")
+	require.NoError(t, err)
+	updated, err := ParseDir("testdata/new")
+	require.NoError(t, err)
+
+	r := Check(old, updated)
+	var changes []string
+	for _, c := range r.Changes {
+		prefix := "compatible: "
+		if c.Breaking {
+			prefix = "breaking: "
+		}
+		changes = append(changes, prefix+c.String())
+	}
+
+	assert.ElementsMatch(t, []string{
+		"breaking: const Version changed: value 1 → 2",
+		"breaking: const KindA changed: value 0 → 1",
+		"breaking: const KindB changed: value 1 → 2",
+		"breaking: method Reader.Close added",
+		"breaking: field Config.Timeout changed: int → int64",
+		"breaking: field Config.Debug removed",
+		"compatible: field Config.Retries added",
+		"breaking: method Client.Get changed: func(string) (string, error) → func(string, string) (string, error)",
+		"breaking: func Remove removed",
+		"compatible: const KindZero added",
+		"compatible: func Added added",
+	}, changes)
+	assert.True(t, r.Breaking())
+}
+
+func TestCheckNoChanges(t *testing.T) {
+	old, err := ParseDir("testdata/old")
+	require.NoError(t, err)
+
+	r := Check(old, old)
+	assert.Empty(t, r.Changes)
+	assert.False(t, r.Breaking())
+}
+
+func TestReportOutput(t *testing.T) {
+	r := &Report{Changes: []*Change{
+		{Name: "Added", Kind: "func", Change: "added"},
+		{Name: "Remove", Kind: "func", Change: "removed", Breaking: true},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteText(&buf))
+	assert.Equal(t, "breaking: func Remove removed\ncompatible: func Added added\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, r.WriteJSON(&buf))
+	var res Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, r, &res)
+}
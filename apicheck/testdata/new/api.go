@@ -0,0 +1,64 @@
+package api
+
+import "io"
+
+// Version of the api
+const Version = 2
+
+const (
+	KindZero = iota
+	KindA
+	KindB
+)
+
+// Reader reads things
+type Reader interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+// Config configures the client
+type Config struct {
+	Addr    string
+	Timeout int64
+	Retries int
+	token   string
+}
+
+// Client talks to the server
+type Client struct {
+	cfg Config
+}
+
+// New creates a client
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Get fetches the key
+func (c *Client) Get(ctx string, key string) (string, error) {
+	return key, nil
+}
+
+// Copy copies all the data
+func Copy(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+// Join joins the parts
+func Join(parts []string, sep string) string {
+	s := ""
+	for i, p := range parts {
+		if i > 0 {
+			s += sep
+		}
+		s += p
+	}
+	return s
+}
+
+// Added is a new function
+func Added() {}
+
+var DefaultTimeout = 10
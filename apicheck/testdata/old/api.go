@@ -0,0 +1,49 @@
+package api
+
+import "io"
+
+// Version of the api
+const Version = 1
+
+const (
+	KindA = iota
+	KindB
+)
+
+// Reader reads things
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+// Config configures the client
+type Config struct {
+	Addr    string
+	Timeout int
+	Debug   bool
+	secret  string
+}
+
+// Client talks to the server
+type Client struct {
+	cfg Config
+}
+
+// New creates a client
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// Get fetches the key
+func (c *Client) Get(key string) (string, error) {
+	return key, nil
+}
+
+// Copy copies all the data
+func Copy(w io.Writer, r io.Reader) error {
+	_, err := io.Copy(w, r)
+	return err
+}
+
+func helper() {}
+
+var DefaultTimeout = 10
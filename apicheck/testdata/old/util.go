@@ -0,0 +1,16 @@
+package api
+
+// Join joins the parts
+func Join(parts []string, sep string) string {
+	s := ""
+	for i, p := range parts {
+		if i > 0 {
+			s += sep
+		}
+		s += p
+	}
+	return s
+}
+
+// Remove is removed in the new version
+func Remove() {}
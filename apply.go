@@ -0,0 +1,150 @@
+package gum
+
+import (
+	"fmt"
+)
+
+// Apply returns a copy of the tree transformed by the actions
+//
+// The tree must be the src tree of the actions or a tree of the same content,
+// nodes of src are found by their ids. Nodes of the result keep Meta of the original nodes,
+// inserted nodes keep Meta of the dst nodes.
+func Apply(t *Tree, actions []*Action) (*Tree, error) {
+	ap := newApplier(t)
+	for _, a := range actions {
+		if err := ap.apply(a); err != nil {
+			return nil, err
+		}
+	}
+
+	ap.root.Refresh()
+	return ap.root, nil
+}
+
+// applier applies actions one by one to a copy of a tree
+type applier struct {
+	root *Tree
+	byID map[int]*Tree
+	// inserted nodes are found by pointers because they have ids of dst tree
+	inserted map[*Tree]*Tree
+	// dst nodes of inserted copies
+	origins map[*Tree]*Tree
+}
+
+func newApplier(t *Tree) *applier {
+	ap := &applier{
+		root:     t.clone(),
+		byID:     make(map[int]*Tree),
+		inserted: make(map[*Tree]*Tree),
+		origins:  make(map[*Tree]*Tree),
+	}
+	for _, n := range PostOrder(ap.root) {
+		ap.byID[n.id] = n
+	}
+	return ap
+}
+
+// find returns the copy of a node of the original tree or of an inserted node
+func (ap *applier) find(n *Tree) (*Tree, error) {
+	if c, ok := ap.inserted[n]; ok {
+		return c, nil
+	}
+	c, ok := ap.byID[n.id]
+	if !ok || c.Type != n.Type {
+		return nil, fmt.Errorf("node %s with id %d isn't found in the tree", n, n.id)
+	}
+	return c, nil
+}
+
+func (ap *applier) add(parent *Tree, pos int, child *Tree) error {
+	if pos < 0 || pos > len(parent.Children) {
+		return fmt.Errorf("position %d is out of children of %s", pos, parent)
+	}
+	parent.addChild(pos, child)
+	child.parent = parent
+	return nil
+}
+
+func (ap *applier) remove(n *Tree) error {
+	if n.parent == nil {
+		return fmt.Errorf("root %s can't be removed", n)
+	}
+	n.parent.removeChild(n)
+	n.parent = nil
+	return nil
+}
+
+func (ap *applier) apply(a *Action) error {
+	switch a.Type {
+	case Insert, InsertTree, Move:
+		parent, err := ap.find(a.Parent)
+		if err != nil {
+			return err
+		}
+
+		var n *Tree
+		pos := a.Pos
+		switch a.Type {
+		case Insert:
+			n = a.Node.clone()
+			n.Children = nil
+			ap.inserted[a.Node] = n
+			ap.origins[n] = a.Node
+		case InsertTree:
+			n = a.Node.clone()
+			dsts, clones := PreOrder(a.Node), PreOrder(n)
+			for i := range dsts {
+				ap.inserted[dsts[i]] = clones[i]
+				ap.origins[clones[i]] = dsts[i]
+			}
+		case Move:
+			if n, err = ap.find(a.Node); err != nil {
+				return err
+			}
+			// position in the same parent is counted before the node is removed
+			if n.parent == parent && a.Pos > positionInParent(n) {
+				pos--
+			}
+			if err := ap.remove(n); err != nil {
+				return err
+			}
+		}
+		return ap.add(parent, pos, n)
+	case Delete, DeleteTree:
+		n, err := ap.find(a.Node)
+		if err != nil {
+			return err
+		}
+		return ap.remove(n)
+	case Update:
+		n, err := ap.find(a.Node)
+		if err != nil {
+			return err
+		}
+		n.Value = a.Value
+	case UpdateAttr:
+		n, err := ap.find(a.Node)
+		if err != nil {
+			return err
+		}
+		// attributes are shared with the original tree
+		n.Attrs = append([]Attr(nil), n.Attrs...)
+		n.SetAttr(a.Key, a.Value)
+	case DeleteAttr:
+		n, err := ap.find(a.Node)
+		if err != nil {
+			return err
+		}
+		attrs := make([]Attr, 0, len(n.Attrs))
+		for _, attr := range n.Attrs {
+			if attr.Key != a.Key {
+				attrs = append(attrs, attr)
+			}
+		}
+		n.Attrs = attrs
+	default:
+		return fmt.Errorf("unsupported action %s", a)
+	}
+
+	return nil
+}
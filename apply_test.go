@@ -0,0 +1,35 @@
+package gum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	require := require.New(t)
+
+	for _, dir := range []string{"testdata/paper", "testdata/actions"} {
+		orgSrc, orgDst := readFixtures(dir+"/src.json", dir+"/dst.json")
+		src, dst := readFixtures(dir+"/src.json", dir+"/dst.json")
+		actions := Patch(src, dst, Match(src, dst))
+
+		changed, err := Apply(src, actions)
+		require.NoError(err)
+		require.Equal(treeString(dst), treeString(changed), dir)
+		require.True(changed.IsIsomorphicTo(dst), dir)
+
+		deepCompare(t, orgSrc, src)
+		deepCompare(t, orgDst, dst)
+
+		// a copy of src is transformed the same way
+		copySrc, _ := readFixtures(dir+"/src.json", dir+"/dst.json")
+		changed, err = Apply(copySrc, actions)
+		require.NoError(err)
+		require.Equal(treeString(dst), treeString(changed), dir)
+
+		// actions don't belong to another tree
+		_, err = Apply(dst, actions)
+		require.Error(err, dir)
+	}
+}
@@ -1,5 +1,9 @@
 package gum
 
+import (
+	"sort"
+)
+
 // bottomUpMatcher implement bottom-up phase of GumTree algorithm
 //
 // it looks for container mappings first
@@ -87,6 +91,10 @@ func (m *bottomUpMatcher) Match(src, dst *Tree) *mappingStore {
 // for descendants of container nodes without previously matched nodes
 // if any of result trees have a size smaller than maxSize
 func (m *bottomUpMatcher) lastChanceMatch(src, dst *Tree) {
+	if hasUnorderedChildren(src, dst) {
+		m.matchUnorderedChildren(src, dst)
+	}
+
 	cSrc := src.clone()
 	cDst := dst.clone()
 
@@ -99,11 +107,15 @@ func (m *bottomUpMatcher) lastChanceMatch(src, dst *Tree) {
 	if cSrc.size < m.maxSize || cDst.size < m.maxSize {
 		zsm := newZsMatcher()
 		zsm.Match(cSrc, cDst)
+		var sets, ordered []Mapping
 		for lt, rt := range zsm.mappings.srcs {
 			left := m.srcIds[lt.id]
 			right := m.dstIds[rt.id]
 
-			if left.id == src.id || right.id == dst.id {
+			if inMappedSet(lt, zsm.mappings) {
+				// children of sets are matched regardless of the order below
+				continue
+			} else if left.id == src.id || right.id == dst.id {
 				//fmt.Printf("Trying to map already mapped source node (%v == %v || %v == %v)\n", left, src, right, dst)
 				continue
 			} else if !m.isMappingAllowed(left, right) {
@@ -114,14 +126,123 @@ func (m *bottomUpMatcher) lastChanceMatch(src, dst *Tree) {
 				continue
 			} else {
 				m.addMapping(left, right)
+				if hasUnorderedChildren(left, right) {
+					sets = append(sets, Mapping{left, right})
+				} else if len(left.Children) > 0 && len(right.Children) > 0 {
+					ordered = append(ordered, Mapping{left, right})
+				}
 			}
 		}
+
+		sort.Slice(sets, func(i, j int) bool { return sets[i][0].id < sets[j][0].id })
+		for _, set := range sets {
+			m.matchUnorderedChildren(set[0], set[1])
+		}
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i][0].id < ordered[j][0].id })
+		for _, p := range ordered {
+			m.matchReorderedChildren(p[0], p[1])
+		}
+	}
+	if !hasUnorderedChildren(src, dst) {
+		m.matchReorderedChildren(src, dst)
 	}
 
 	putTrees(m.mappedSrc, src)
 	putTrees(m.mappedDst, dst)
 }
 
+// matchUnorderedChildren maps children of sets with the same type and label,
+// Zhang Shasha algorithm would map only children in the same order.
+// The most similar candidates are mapped first.
+func (m *bottomUpMatcher) matchUnorderedChildren(src, dst *Tree) {
+	var candidates []Mapping
+	similarities := make(map[Mapping]float64)
+	for _, s := range src.Children {
+		for _, d := range dst.Children {
+			if s.Value == d.Value && m.isMappingAllowed(s, d) {
+				c := Mapping{s, d}
+				candidates = append(candidates, c)
+				similarities[c] = m.childSimilarity(s, d)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return similarities[candidates[i]] > similarities[candidates[j]]
+	})
+	for _, c := range candidates {
+		if m.isMappingAllowed(c[0], c[1]) {
+			m.lastChanceMatch(c[0], c[1])
+			m.addMapping(c[0], c[1])
+		}
+	}
+}
+
+// matchReorderedChildren maps unmatched isomorphic children of ordered nodes,
+// Zhang Shasha algorithm keeps the order of children and leaves reordered ones unmatched
+func (m *bottomUpMatcher) matchReorderedChildren(src, dst *Tree) {
+	for _, s := range src.Children {
+		for _, d := range dst.Children {
+			if !m.isMappingAllowed(s, d) || !s.IsIsomorphicTo(d) || !m.isUnmatchedSubtree(s, d) {
+				continue
+			}
+			srcs, dsts := PreOrder(s), PreOrder(d)
+			for i := range srcs {
+				m.addMapping(srcs[i], dsts[i])
+			}
+			break
+		}
+	}
+}
+
+// isUnmatchedSubtree returns true if no nodes of the isomorphic subtrees are matched
+func (m *bottomUpMatcher) isUnmatchedSubtree(src, dst *Tree) bool {
+	srcs, dsts := PreOrder(src), PreOrder(dst)
+	for i := range srcs {
+		if m.isSrcMatched(srcs[i]) || m.isDstMatched(dsts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// childSimilarity prefers children with mapped descendants in common
+// and then children with more descendants of the same type and label
+func (m *bottomUpMatcher) childSimilarity(src, dst *Tree) float64 {
+	srcDesc := getDescendants(src)
+	dstDesc := getDescendants(dst)
+	if len(srcDesc) == 0 && len(dstDesc) == 0 {
+		return 0
+	}
+
+	labels := make(map[string]int)
+	for _, t := range srcDesc {
+		labels[t.String()]++
+	}
+	sameLabels := 0
+	for _, t := range dstDesc {
+		if labels[t.String()] > 0 {
+			labels[t.String()]--
+			sameLabels++
+		}
+	}
+
+	// dice coefficients, mapped descendants weigh more
+	total := float64(len(srcDesc) + len(dstDesc))
+	mapped := 2 * float64(m.numberOfCommonDescendants(srcDesc, dstDesc)) / total
+	return 100*mapped + 2*float64(sameLabels)/total
+}
+
+// inMappedSet returns true if the node of the cloned tree is a descendant of a set mapped to a set
+func inMappedSet(t *Tree, mappings *mappingStore) bool {
+	for p := t.parent; p != nil && p.parent != nil; p = p.parent {
+		if d, ok := mappings.GetDst(p); ok && hasUnorderedChildren(p, d) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *bottomUpMatcher) isMappingAllowed(src, dst *Tree) bool {
 	return src.Type == dst.Type && !(m.isSrcMatched(src) || m.isDstMatched(dst))
 }
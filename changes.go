@@ -0,0 +1,80 @@
+package gum
+
+// Change is a kind of change of a node made by actions
+type Change int8
+
+const (
+	// Unchanged node isn't changed itself, but its descendants may be
+	Unchanged Change = iota
+	// Inserted node exists only in dst tree
+	Inserted
+	// Deleted node exists only in src tree
+	Deleted
+	// Updated node has a different label or attributes in the other tree
+	Updated
+	// Moved node has a different parent or position in the other tree
+	Moved
+)
+
+func (c Change) String() string {
+	switch c {
+	case Unchanged:
+		return "unchanged"
+	case Inserted:
+		return "inserted"
+	case Deleted:
+		return "deleted"
+	case Updated:
+		return "updated"
+	case Moved:
+		return "moved"
+	default:
+		return "unknown change"
+	}
+}
+
+// Changes returns changed nodes of src and dst trees made by the actions
+//
+// All nodes of inserted and deleted subtrees are included.
+// Updated and moved nodes are included with their partners in the other tree,
+// a node that is moved and updated is reported as moved.
+func Changes(actions []*Action, mappings []Mapping) (map[*Tree]Change, map[*Tree]Change) {
+	srcToDst := make(map[*Tree]*Tree, len(mappings))
+	for _, m := range mappings {
+		srcToDst[m[0]] = m[1]
+	}
+
+	src := make(map[*Tree]Change)
+	dst := make(map[*Tree]Change)
+	set := func(t *Tree, c Change) {
+		if src[t] != Moved {
+			src[t] = c
+		}
+		if d, ok := srcToDst[t]; ok && dst[d] != Moved {
+			dst[d] = c
+		}
+	}
+
+	for _, a := range actions {
+		switch a.Type {
+		case Insert:
+			dst[a.Node] = Inserted
+		case InsertTree:
+			for _, n := range PreOrder(a.Node) {
+				dst[n] = Inserted
+			}
+		case Delete:
+			src[a.Node] = Deleted
+		case DeleteTree:
+			for _, n := range PreOrder(a.Node) {
+				src[n] = Deleted
+			}
+		case Update, UpdateAttr, DeleteAttr:
+			set(a.Node, Updated)
+		case Move:
+			set(a.Node, Moved)
+		}
+	}
+
+	return src, dst
+}
@@ -0,0 +1,46 @@
+package gum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanges(t *testing.T) {
+	src, err := treeFromJSON(`{"root": {"typeLabel": "Block", "children": [
+		{"typeLabel": "Name", "label": "a", "children": []},
+		{"typeLabel": "Name", "label": "b", "children": []},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "d", "children": []}
+		]}
+	]}}`)
+	require.NoError(t, err)
+	dst, err := treeFromJSON(`{"root": {"typeLabel": "Block", "children": [
+		{"typeLabel": "Name", "label": "c", "children": []},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "b", "children": []}
+		]}
+	]}}`)
+	require.NoError(t, err)
+
+	mappings := []Mapping{
+		{src, dst},
+		{src.Children[0], dst.Children[0]},
+		{src.Children[1], dst.Children[1].Children[0]},
+	}
+	srcChanges, dstChanges := Changes(Patch(src, dst, mappings), mappings)
+
+	assert.Equal(t, map[*Tree]Change{
+		src.Children[0]:             Updated,
+		src.Children[1]:             Moved,
+		src.Children[2]:             Deleted,
+		src.Children[2].Children[0]: Deleted,
+	}, srcChanges)
+	assert.Equal(t, map[*Tree]Change{
+		dst.Children[0]:             Updated,
+		dst.Children[1]:             Inserted,
+		dst.Children[1].Children[0]: Moved,
+	}, dstChanges)
+	assert.Equal(t, "moved", Moved.String())
+}
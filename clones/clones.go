@@ -0,0 +1,240 @@
+package clones
+
+import (
+	"crypto/md5"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+const defaultMinHeight = 3
+
+// Kind of a clone
+type Kind int8
+
+const (
+	_ Kind = iota
+	// Type1 clones are exact copies
+	Type1
+	// Type2 clones are copies with different identifiers and literals
+	Type2
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Type1:
+		return "type-1"
+	case Type2:
+		return "type-2"
+	default:
+		return "unknown kind"
+	}
+}
+
+// Occurrence is a single copy of the cloned code
+type Occurrence struct {
+	File string
+	// Path from the root of the file to the node
+	// in the form of /Type[position in parent]/...
+	Path string
+	// Size is number of nodes in the subtree
+	Size int
+	Tree *gum.Tree
+}
+
+// Clone is a group of subtrees considered to be copies of each other
+type Clone struct {
+	Kind        Kind
+	Occurrences []*Occurrence
+}
+
+// Index collects subtrees of many trees bucketed by hashes
+type Index struct {
+	// MinHeight limits nodes considered as clones
+	// recommended MinHeight = 3 to avoid reporting every repeated expression
+	MinHeight int
+	// Abstracted are types of identifiers and literals,
+	// only their labels are ignored when subtrees are compared for Type-2 clones
+	Abstracted map[string]bool
+
+	exact map[[16]byte][]*entry
+	shape map[[16]byte][]*entry
+
+	shapeHashes map[*gum.Tree][16]byte
+}
+
+type entry struct {
+	file string
+	tree *gum.Tree
+}
+
+// NewIndex creates new empty Index with default (recommended) parameters
+func NewIndex() *Index {
+	return &Index{
+		MinHeight:   defaultMinHeight,
+		Abstracted:  map[string]bool{"Ident": true, "BasicLit": true},
+		exact:       make(map[[16]byte][]*entry),
+		shape:       make(map[[16]byte][]*entry),
+		shapeHashes: make(map[*gum.Tree][16]byte),
+	}
+}
+
+// Add puts all subtrees of the tree into the index.
+// The tree must be Refresh'ed.
+func (idx *Index) Add(file string, t *gum.Tree) {
+	idx.addShapeHashes(t)
+
+	for _, n := range gum.PreOrder(t) {
+		if n.GetHeight() < idx.MinHeight {
+			continue
+		}
+
+		e := &entry{file: file, tree: n}
+		idx.exact[n.GetHash()] = append(idx.exact[n.GetHash()], e)
+		idx.shape[idx.shapeHashes[n]] = append(idx.shape[idx.shapeHashes[n]], e)
+	}
+}
+
+// Clones returns maximal groups of clones found in the index
+// sorted by size of the cloned subtrees, the biggest go first
+func (idx *Index) Clones() []*Clone {
+	var clones []*Clone
+
+	for _, entries := range idx.exact {
+		if len(entries) < 2 || idx.isSubsumed(entries, idx.exact, exactHash) {
+			continue
+		}
+		clones = append(clones, newClone(Type1, entries))
+	}
+
+	for _, entries := range idx.shape {
+		if len(entries) < 2 || idx.isSubsumed(entries, idx.shape, idx.shapeHash) {
+			continue
+		}
+		// all copies are exact, it's already reported as type-1
+		if sameHash(entries, exactHash) {
+			continue
+		}
+		clones = append(clones, newClone(Type2, entries))
+	}
+
+	sort.Slice(clones, func(i, j int) bool {
+		a, b := clones[i], clones[j]
+		if a.Occurrences[0].Size != b.Occurrences[0].Size {
+			return a.Occurrences[0].Size > b.Occurrences[0].Size
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return occurrenceLess(a.Occurrences[0], b.Occurrences[0])
+	})
+
+	return clones
+}
+
+// group is subsumed when parents of all subtrees are clones of each other too
+func (idx *Index) isSubsumed(entries []*entry, buckets map[[16]byte][]*entry, hash func(*gum.Tree) [16]byte) bool {
+	var parents []*entry
+	for _, e := range entries {
+		p := e.tree.GetParent()
+		if p == nil {
+			return false
+		}
+		parents = append(parents, &entry{file: e.file, tree: p})
+	}
+
+	if !sameHash(parents, hash) {
+		return false
+	}
+
+	return len(buckets[hash(parents[0].tree)]) > 1
+}
+
+func (idx *Index) shapeHash(t *gum.Tree) [16]byte {
+	return idx.shapeHashes[t]
+}
+
+// label-free hash ignores values of identifiers and literals
+// which makes subtrees that differ only in them equal,
+// operators and other tokens are kept
+func (idx *Index) addShapeHashes(t *gum.Tree) string {
+	var b strings.Builder
+	b.WriteString("[(" + t.Type)
+	if !idx.Abstracted[t.Type] {
+		b.WriteString("_" + t.Value)
+		for _, a := range t.Attrs {
+			b.WriteString("{" + a.Key + "=" + a.Value + "}")
+		}
+	}
+	for _, c := range t.Children {
+		b.WriteString(idx.addShapeHashes(c))
+	}
+	b.WriteString(")]")
+
+	s := b.String()
+	idx.shapeHashes[t] = md5.Sum([]byte(s))
+	return s
+}
+
+func exactHash(t *gum.Tree) [16]byte {
+	return t.GetHash()
+}
+
+func sameHash(entries []*entry, hash func(*gum.Tree) [16]byte) bool {
+	h := hash(entries[0].tree)
+	for _, e := range entries[1:] {
+		if hash(e.tree) != h {
+			return false
+		}
+	}
+	return true
+}
+
+func newClone(kind Kind, entries []*entry) *Clone {
+	c := &Clone{Kind: kind, Occurrences: make([]*Occurrence, len(entries))}
+	for i, e := range entries {
+		c.Occurrences[i] = &Occurrence{
+			File: e.file,
+			Path: NodePath(e.tree),
+			Size: e.tree.GetSize(),
+			Tree: e.tree,
+		}
+	}
+	sort.Slice(c.Occurrences, func(i, j int) bool { return occurrenceLess(c.Occurrences[i], c.Occurrences[j]) })
+
+	return c
+}
+
+func occurrenceLess(a, b *Occurrence) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.Path < b.Path
+}
+
+// NodePath returns path from the root to the node
+// in the form of /Type[position in parent]/...
+func NodePath(t *gum.Tree) string {
+	var parts []string
+	for t.GetParent() != nil {
+		p := t.GetParent()
+		pos := 0
+		for i, c := range p.Children {
+			if c == t {
+				pos = i
+				break
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s[%d]", t.Type, pos))
+		t = p
+	}
+	parts = append(parts, t.Type)
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+
+	return "/" + strings.Join(parts, "/")
+}
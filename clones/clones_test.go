@@ -0,0 +1,121 @@
+package clones
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fileA = `package a
+
+func handleA(w Writer, r *Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(405)
+		return
+	}
+	w.Write([]byte("a"))
+}
+`
+
+const fileB = `package b
+
+func handleB(w Writer, r *Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(405)
+		return
+	}
+	w.Write([]byte("a"))
+}
+
+func handleC(resp Writer, req *Request) {
+	if req.Method != "POST" {
+		resp.WriteHeader(400)
+		return
+	}
+	resp.Write([]byte("c"))
+}
+`
+
+func parse(t *testing.T, src string) *gum.Tree {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	require.NoError(t, err)
+	return golang.ToTree(f)
+}
+
+func TestClones(t *testing.T) {
+	assert := assert.New(t)
+
+	idx := NewIndex()
+	idx.Add("a.go", parse(t, fileA))
+	idx.Add("b.go", parse(t, fileB))
+
+	clones := idx.Clones()
+	require.Len(t, clones, 3)
+
+	// handlers differ only in identifiers and literals
+	c := clones[0]
+	assert.Equal(Type2, c.Kind)
+	require.Len(t, c.Occurrences, 3)
+	assert.Equal("a.go", c.Occurrences[0].File)
+	assert.Equal("/File/FuncDecl[1]", c.Occurrences[0].Path)
+	assert.Equal("b.go", c.Occurrences[1].File)
+	assert.Equal("/File/FuncDecl[1]", c.Occurrences[1].Path)
+	assert.Equal("b.go", c.Occurrences[2].File)
+	assert.Equal("/File/FuncDecl[2]", c.Occurrences[2].Path)
+	assert.Equal(35, c.Occurrences[0].Size)
+
+	// only the biggest exact copies are reported, not their parts
+	c = clones[1]
+	assert.Equal(Type1, c.Kind)
+	require.Len(t, c.Occurrences, 2)
+	assert.Equal("/File/FuncDecl[1]/BlockStmt[2]", c.Occurrences[0].Path)
+	assert.Equal("/File/FuncDecl[1]/BlockStmt[2]", c.Occurrences[1].Path)
+
+	c = clones[2]
+	assert.Equal(Type1, c.Kind)
+	require.Len(t, c.Occurrences, 2)
+	assert.Equal("/File/FuncDecl[1]/FuncType[1]", c.Occurrences[0].Path)
+}
+
+func TestClonesMinHeight(t *testing.T) {
+	idx := NewIndex()
+	idx.MinHeight = 100
+	idx.Add("a.go", parse(t, fileA))
+	idx.Add("b.go", parse(t, fileB))
+
+	assert.Len(t, idx.Clones(), 0)
+}
+
+func TestClonesOperators(t *testing.T) {
+	src := `package a
+
+func add(a, b int) int {
+	return a + b
+}
+
+func sub(x, y int) int {
+	return x - y
+}
+`
+	idx := NewIndex()
+	idx.Add("a.go", parse(t, src))
+	// only the parameters are the same
+	for _, c := range idx.Clones() {
+		assert.NotEqual(t, "FuncDecl", c.Occurrences[0].Tree.Type)
+	}
+
+	idx = NewIndex()
+	idx.Add("a.go", parse(t, strings.Replace(src, "x - y", "x + y", 1)))
+	clones := idx.Clones()
+	require.NotEmpty(t, clones)
+	assert.Equal(t, Type2, clones[0].Kind)
+	assert.Equal(t, "/File/FuncDecl[1]", clones[0].Occurrences[0].Path)
+	assert.Equal(t, "/File/FuncDecl[2]", clones[0].Occurrences[1].Path)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/smacker/gum/apicheck"
+)
+
+// apicheckCommand reports changes of exported api between two versions of a go package
+type apicheckCommand struct {
+	Mode string `short:"m" long:"mode" default:"text" choice:"text" choice:"json"`
+	Fail bool   `long:"fail" description:"exit with non-zero status if there are breaking changes"`
+	Args struct {
+		Old string
+		New string
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *apicheckCommand) Execute(args []string) error {
+	old, err := apicheck.ParseDir(c.Args.Old)
+	if err != nil {
+		return err
+	}
+	updated, err := apicheck.ParseDir(c.Args.New)
+	if err != nil {
+		return err
+	}
+
+	r := apicheck.Check(old, updated)
+	if c.Mode == "json" {
+		err = r.WriteJSON(os.Stdout)
+	} else {
+		err = r.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Fail && r.Breaking() {
+		return errors.New("breaking changes found")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smacker/gum/clones"
+	"github.com/smacker/gum/golang"
+)
+
+type clonesCommand struct {
+	MinHeight int `long:"min-height" default:"3" description:"minimal height of a cloned subtree"`
+	Args      struct {
+		Dir string
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *clonesCommand) Execute(args []string) error {
+	idx := clones.NewIndex()
+	idx.MinHeight = c.MinHeight
+
+	err := filepath.Walk(c.Args.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != c.Args.Dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("can't parse the file %s: %s", path, err)
+		}
+		rel, err := filepath.Rel(c.Args.Dir, path)
+		if err != nil {
+			return err
+		}
+		idx.Add(rel, golang.ToTree(f))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cls := idx.Clones()
+	jsonClones := make([]*jsonClone, len(cls))
+	for i, cl := range cls {
+		jc := &jsonClone{Type: cl.Kind.String()}
+		for _, o := range cl.Occurrences {
+			jc.Occurrences = append(jc.Occurrences, &jsonOccurrence{
+				File: o.File,
+				Path: o.Path,
+				Size: o.Size,
+			})
+		}
+		jsonClones[i] = jc
+	}
+
+	b, err := json.MarshalIndent(struct {
+		Clones []*jsonClone `json:"clones"`
+	}{jsonClones}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+
+	return nil
+}
+
+type jsonClone struct {
+	Type        string            `json:"type"`
+	Occurrences []*jsonOccurrence `json:"occurrences"`
+}
+
+type jsonOccurrence struct {
+	File string `json:"file"`
+	Path string `json:"path"`
+	Size int    `json:"size"`
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+type gitCommand struct {
+	parserOptions
+	termOptions
+	Mode string `short:"m" long:"mode" default:"text" choice:"text" choice:"json" choice:"webdiff" choice:"term"`
+	Repo string `short:"C" long:"repo" default:"." description:"path to the git repository"`
+	Args struct {
+		Rev1  string
+		Rev2  string
+		Paths []string
+	} `positional-args:"yes" required:"yes"`
+}
+
+// git calls external diff driver as:
+// path old-file old-hex old-mode new-file new-hex new-mode [new-path xfrm-info]
+var gitModeRe = regexp.MustCompile(`^([0-7]{6}|\.)$`)
+
+func (c *gitCommand) Execute(args []string) error {
+	var files []*gitFileDiff
+	var err error
+	if c.isExternalDiff() {
+		files, err = c.externalDiffFiles()
+	} else {
+		files, err = c.revisionFiles()
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.output(os.Stdout, files)
+}
+
+func (c *gitCommand) isExternalDiff() bool {
+	p := c.Args.Paths
+	return (len(p) == 5 || len(p) == 7) && gitModeRe.MatchString(p[1]) && gitModeRe.MatchString(p[4])
+}
+
+// files for GIT_EXTERNAL_DIFF calling convention
+func (c *gitCommand) externalDiffFiles() ([]*gitFileDiff, error) {
+	path := c.Args.Rev1
+	oldFile := c.Args.Rev2
+	newFile := c.Args.Paths[2]
+
+	if !isSupportedFile(path, c.Parser) {
+		return nil, nil
+	}
+
+	f := &gitFileDiff{}
+	if oldFile != os.DevNull {
+		f.Src = path
+		b, err := ioutil.ReadFile(oldFile)
+		if err != nil {
+			return nil, err
+		}
+		f.SrcContent = b
+	}
+	if newFile != os.DevNull {
+		f.Dst = path
+		// renamed file
+		if len(c.Args.Paths) == 7 {
+			f.Dst = c.Args.Paths[5]
+		}
+		b, err := ioutil.ReadFile(newFile)
+		if err != nil {
+			return nil, err
+		}
+		f.DstContent = b
+	}
+
+	return []*gitFileDiff{f}, nil
+}
+
+// files changed between two revisions
+func (c *gitCommand) revisionFiles() ([]*gitFileDiff, error) {
+	repo := &gitRepo{Dir: c.Repo}
+	changes, err := repo.ChangedFiles(c.Args.Rev1, c.Args.Rev2, c.Args.Paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*gitFileDiff
+	for _, ch := range changes {
+		if ch.Src != "" && !isSupportedFile(ch.Src, c.Parser) ||
+			ch.Dst != "" && !isSupportedFile(ch.Dst, c.Parser) {
+			continue
+		}
+
+		f := &gitFileDiff{Src: ch.Src, Dst: ch.Dst}
+		if ch.Src != "" {
+			if f.SrcContent, err = repo.ReadBlob(c.Args.Rev1, ch.Src); err != nil {
+				return nil, err
+			}
+		}
+		if ch.Dst != "" {
+			if f.DstContent, err = repo.ReadBlob(c.Args.Rev2, ch.Dst); err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+func (c *gitCommand) output(w io.Writer, files []*gitFileDiff) error {
+	for _, f := range files {
+		if err := f.diff(c.parserOptions); err != nil {
+			return err
+		}
+	}
+
+	switch c.Mode {
+	case "text":
+		for _, f := range files {
+			writeTextDiff(w, f)
+		}
+		return nil
+	case "json":
+		jsonFiles := make([]*jsonFileDiff, len(files))
+		for i, f := range files {
+			jsonFiles[i] = &jsonFileDiff{Src: f.Src, Dst: f.Dst}
+			if f.SrcTree != nil && f.DstTree != nil {
+				jsonFiles[i].DiffDocument = gum.NewDiffDocument(f.Mappings, f.Actions)
+			}
+		}
+		b, err := json.MarshalIndent(struct {
+			Files []*jsonFileDiff `json:"files"`
+		}{jsonFiles}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+		return nil
+	case "term":
+		for _, f := range files {
+			if f.SrcTree == nil || f.DstTree == nil {
+				writeTextDiff(w, f)
+				continue
+			}
+
+			fmt.Fprintln(w, termHeader+"diff "+f.Src+" "+f.Dst+termReset)
+			if err := writeTermDiff(w, f.SrcContent, f.DstContent, f.SrcTree, f.DstTree, c.termOptions); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "webdiff":
+		for _, f := range files {
+			if f.SrcTree == nil || f.DstTree == nil {
+				continue
+			}
+
+			htmlf, err := ioutil.TempFile("", "gum_diff_*.html")
+			if err != nil {
+				return err
+			}
+			if err := writeWebdiff(htmlf, f.SrcContent, f.DstContent, f.SrcTree, f.DstTree); err != nil {
+				return err
+			}
+			if err := htmlf.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(w, "html file:", htmlf.Name())
+			openFile(htmlf.Name())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %s", c.Mode)
+	}
+}
+
+func writeTextDiff(w io.Writer, f *gitFileDiff) {
+	switch {
+	case f.SrcTree == nil:
+		fmt.Fprintf(w, "added %s\n", f.Dst)
+		return
+	case f.DstTree == nil:
+		fmt.Fprintf(w, "deleted %s\n", f.Src)
+		return
+	case f.Src != f.Dst:
+		fmt.Fprintf(w, "diff %s -> %s\n", f.Src, f.Dst)
+	default:
+		fmt.Fprintf(w, "diff %s\n", f.Src)
+	}
+
+	for _, a := range f.Actions {
+		fmt.Fprintln(w, a.String())
+	}
+}
+
+type gitFileDiff struct {
+	// Empty for added files
+	Src        string
+	SrcContent []byte
+	// Empty for deleted files
+	Dst        string
+	DstContent []byte
+
+	SrcTree  *gum.Tree
+	DstTree  *gum.Tree
+	Mappings []gum.Mapping
+	Actions  []*gum.Action
+}
+
+func (f *gitFileDiff) diff(opts parserOptions) error {
+	var err error
+	if f.Src != "" {
+		if f.SrcTree, err = parseContent(f.Src, f.SrcContent, opts); err != nil {
+			return err
+		}
+	}
+	if f.Dst != "" {
+		if f.DstTree, err = parseContent(f.Dst, f.DstContent, opts); err != nil {
+			return err
+		}
+	}
+	if f.SrcTree != nil && f.DstTree != nil {
+		f.Mappings = gum.Match(f.SrcTree, f.DstTree)
+		f.Actions = gum.Patch(f.SrcTree, f.DstTree, f.Mappings)
+	}
+
+	return nil
+}
+
+// gitRepo reads local git repository using git binary
+type gitRepo struct {
+	Dir string
+}
+
+// gitChange is a file changed between revisions
+type gitChange struct {
+	// Empty for added files
+	Src string
+	// Empty for deleted files
+	Dst string
+}
+
+// ChangedFiles returns files changed between two revisions, renames are detected by git
+func (r *gitRepo) ChangedFiles(rev1, rev2 string, paths ...string) ([]*gitChange, error) {
+	args := []string{"diff", "--name-status", "-z", "--find-renames", rev1, rev2, "--"}
+	out, err := r.run(append(args, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*gitChange
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		path := fields[i+1]
+		switch status[0] {
+		case 'A':
+			changes = append(changes, &gitChange{Dst: path})
+		case 'D':
+			changes = append(changes, &gitChange{Src: path})
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("unexpected output of git diff: %q", out)
+			}
+			changes = append(changes, &gitChange{Src: path, Dst: fields[i+2]})
+			i++
+		default:
+			changes = append(changes, &gitChange{Src: path, Dst: path})
+		}
+	}
+
+	return changes, nil
+}
+
+// ReadBlob returns content of the file at the revision
+func (r *gitRepo) ReadBlob(rev, path string) ([]byte, error) {
+	return r.run("show", rev+":"+path)
+}
+
+func (r *gitRepo) run(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gitSrc = `package foo
+
+func Foo() int {
+	return 1
+}
+`
+
+const gitDst = `package foo
+
+func Foo() int {
+	return 2
+}
+`
+
+func newTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary isn't available")
+	}
+
+	dir, err := ioutil.TempDir("", "gum_git_test")
+	require.NoError(t, err)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=gum", "GIT_AUTHOR_EMAIL=gum@example.com",
+			"GIT_COMMITTER_NAME=gum", "GIT_COMMITTER_EMAIL=gum@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	git("init", "-q")
+	write("foo.go", gitSrc)
+	write("old.go", "package foo\n\nvar bar = []int{1, 2, 3, 4, 5}\n")
+	write("README", "readme")
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+
+	write("foo.go", gitDst)
+	write("README", "changed readme")
+	git("mv", "old.go", "new.go")
+	write("added.go", "package foo\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "second")
+
+	return dir
+}
+
+func TestGitRepo(t *testing.T) {
+	assert := assert.New(t)
+	dir := newTestRepo(t)
+	defer os.RemoveAll(dir)
+
+	repo := &gitRepo{Dir: dir}
+	changes, err := repo.ChangedFiles("HEAD~1", "HEAD")
+	require.NoError(t, err)
+	assert.Equal([]*gitChange{
+		{Src: "README", Dst: "README"},
+		{Dst: "added.go"},
+		{Src: "foo.go", Dst: "foo.go"},
+		{Src: "old.go", Dst: "new.go"},
+	}, changes)
+
+	changes, err = repo.ChangedFiles("HEAD~1", "HEAD", "foo.go")
+	require.NoError(t, err)
+	assert.Equal([]*gitChange{{Src: "foo.go", Dst: "foo.go"}}, changes)
+
+	b, err := repo.ReadBlob("HEAD~1", "foo.go")
+	require.NoError(t, err)
+	assert.Equal(gitSrc, string(b))
+
+	_, err = repo.ReadBlob("HEAD~1", "added.go")
+	assert.Error(err)
+}
+
+func TestGitCommand(t *testing.T) {
+	assert := assert.New(t)
+	dir := newTestRepo(t)
+	defer os.RemoveAll(dir)
+
+	c := &gitCommand{Mode: "text", Repo: dir}
+	c.Parser = "go"
+	c.Args.Rev1 = "HEAD~1"
+	c.Args.Rev2 = "HEAD"
+
+	files, err := c.revisionFiles()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, c.output(&out, files))
+	assert.Equal(`added added.go
+diff foo.go
+update: BasicLit@@1; value: 2
+diff old.go -> new.go
+`, out.String())
+}
+
+func TestGitExternalDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "gum_git_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldFile := filepath.Join(dir, "old")
+	newFile := filepath.Join(dir, "new")
+	require.NoError(t, ioutil.WriteFile(oldFile, []byte(gitSrc), 0644))
+	require.NoError(t, ioutil.WriteFile(newFile, []byte(gitDst), 0644))
+
+	c := &gitCommand{Mode: "text"}
+	c.Parser = "go"
+	c.Args.Rev1 = "foo.go"
+	c.Args.Rev2 = oldFile
+	c.Args.Paths = []string{"d7e0b3a", "100644", newFile, "5f2e0a1", "100644"}
+	assert.True(c.isExternalDiff())
+
+	files, err := c.externalDiffFiles()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, c.output(&out, files))
+	assert.Equal("diff foo.go\nupdate: BasicLit@@1; value: 2\n", out.String())
+
+	c.Args.Paths = []string{"foo.go"}
+	assert.False(c.isExternalDiff())
+}
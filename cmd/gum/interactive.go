@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"text/template"
+
+	"github.com/smacker/gum"
+)
+
+// writeInteractiveWebdiff writes self-contained html page with linked nodes of src and dst,
+// list of actions and arrows for moved nodes
+func writeInteractiveWebdiff(w io.Writer, srcb, dstb []byte, src, dst *gum.Tree) error {
+	mappings := gum.Match(src, dst)
+	actions := gum.Patch(src, dst, mappings)
+	srcGroups, dstGroups := treeGroups(actions, mappings)
+
+	srcToDst := make(map[*gum.Tree]*gum.Tree, len(mappings))
+	dstToSrc := make(map[*gum.Tree]*gum.Tree, len(mappings))
+	for _, m := range mappings {
+		srcToDst[m[0]] = m[1]
+		dstToSrc[m[1]] = m[0]
+	}
+
+	t, err := template.New("webpage").Parse(interactiveTpl)
+	if err != nil {
+		return err
+	}
+
+	srcSpans := linkedTags(src, "s", "d", srcToDst, srcGroups, []string{"mv", "upd", "del"})
+	dstSpans := linkedTags(dst, "d", "s", dstToSrc, dstGroups, []string{"mv", "upd", "add"})
+	dels, inss := labelDiffRanges(actions, mappings, srcb, dstb)
+	for _, r := range dels {
+		srcSpans.add(r[0], r[1], "del")
+	}
+	for _, r := range inss {
+		dstSpans.add(r[0], r[1], "add")
+	}
+
+	return t.Execute(w, struct {
+		SrcHTML string
+		DstHTML string
+		Actions []*interactiveAction
+	}{
+		SrcHTML: genHTML(srcb, srcSpans),
+		DstHTML: genHTML(dstb, dstSpans),
+		Actions: newInteractiveActions(actions, srcToDst),
+	})
+}
+
+// linkedTags returns spans for all nodes with positions,
+// node ids are prefixed with the side to make them unique in the page
+func linkedTags(
+	root *gum.Tree,
+	prefix, otherPrefix string,
+	partners map[*gum.Tree]*gum.Tree,
+	treeGroups map[string][]*gum.Tree,
+	order []string,
+) *tags {
+	tags := newTags()
+	for _, t := range gum.PreOrder(root) {
+		if t.Length == 0 {
+			continue
+		}
+
+		class := "node"
+		for _, g := range order {
+			if inGroup(treeGroups[g], t) {
+				class += " " + g
+				break
+			}
+		}
+
+		tag := fmt.Sprintf("<span class='%s' data-id='%s%d' title='%s'",
+			class, prefix, t.GetID(), html.EscapeString(toPrettyString(t)))
+		if p, ok := partners[t]; ok {
+			tag += fmt.Sprintf(" data-match='%s%d'", otherPrefix, p.GetID())
+		}
+		tags.addSpan(t.Pos, t.End(), tag+">")
+	}
+
+	return tags
+}
+
+// interactiveAction is an item in the list of actions
+type interactiveAction struct {
+	Type string
+	// html-escaped description of the node
+	Description string
+	// id of the node the action is applied to
+	Target string
+	// id of the node on the other side if it exists
+	Other string
+}
+
+func newInteractiveActions(actions []*gum.Action, srcToDst map[*gum.Tree]*gum.Tree) []*interactiveAction {
+	items := make([]*interactiveAction, len(actions))
+	for i, a := range actions {
+		item := &interactiveAction{
+			Type:        a.Type.String(),
+			Description: toPrettyString(a.Node),
+		}
+
+		switch a.Type {
+		case gum.Insert, gum.InsertTree:
+			item.Target = fmt.Sprintf("d%d", a.Node.GetID())
+		default:
+			item.Target = fmt.Sprintf("s%d", a.Node.GetID())
+			if dst, ok := srcToDst[a.Node]; ok {
+				item.Other = fmt.Sprintf("d%d", dst.GetID())
+			}
+		}
+		switch a.Type {
+		case gum.Update:
+			item.Description += " → " + a.Value
+		case gum.UpdateAttr:
+			old, _ := a.Node.GetAttr(a.Key)
+			item.Description += fmt.Sprintf(" %s: %s → %s", a.Key, old, a.Value)
+		case gum.DeleteAttr:
+			old, _ := a.Node.GetAttr(a.Key)
+			item.Description += fmt.Sprintf(" %s: %s", a.Key, old)
+		}
+		// the template doesn't escape values
+		item.Description = html.EscapeString(item.Description)
+
+		items[i] = item
+	}
+
+	return items
+}
+
+const interactiveTpl = `
+<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+    <meta http-equiv="X-UA-Compatible" content="ie=edge" />
+    <title>Webdiff</title>
+    <style>
+      body {
+        margin: 0;
+        display: flex;
+        height: 100vh;
+        font-family: sans-serif;
+      }
+      #actions {
+        width: 20%;
+        overflow: auto;
+        border-right: 1px solid #ddd;
+        font-size: 13px;
+      }
+      #actions ul {
+        list-style: none;
+        margin: 0;
+        padding: 0;
+      }
+      #actions li {
+        padding: 4px 8px;
+        cursor: pointer;
+        border-bottom: 1px solid #eee;
+        white-space: nowrap;
+        overflow: hidden;
+        text-overflow: ellipsis;
+      }
+      #actions li:hover {
+        background: #f3f3f3;
+      }
+      #actions .type {
+        font-weight: bold;
+      }
+      #files {
+        position: relative;
+        flex: 1;
+        display: flex;
+        overflow: auto;
+      }
+      #files > div {
+        width: 50%;
+        padding: 0 16px;
+      }
+      #arrows {
+        position: absolute;
+        top: 0;
+        left: 0;
+        pointer-events: none;
+      }
+      .del {
+        background: #ffeef0;
+      }
+      .add {
+        background: #e6ffed;
+      }
+      .upd {
+        background: #ffffd8;
+      }
+      .upd .del {
+        background: #fdb8c0;
+      }
+      .upd .add {
+        background: #acf2bd;
+      }
+      .mv {
+        background: #efefef;
+      }
+      .hover {
+        outline: 1px solid #0366d6;
+        background: #dbedff;
+      }
+      .selected {
+        outline: 2px solid #e36209;
+      }
+    </style>
+  </head>
+  <body>
+    <div id="actions">
+      <ul>
+        {{- range .Actions }}
+        <li data-target="{{ .Target }}" data-other="{{ .Other }}" title="{{ .Type }} {{ .Description }}">
+          <span class="type">{{ .Type }}</span> {{ .Description }}
+        </li>
+        {{- end }}
+      </ul>
+    </div>
+    <div id="files">
+      <svg id="arrows">
+        <defs>
+          <marker id="head" markerWidth="8" markerHeight="8" refX="8" refY="4" orient="auto">
+            <path d="M0,0 L8,4 L0,8 z" fill="#888" />
+          </marker>
+        </defs>
+      </svg>
+      <div id="src">
+        <pre>{{ .SrcHTML }}</pre>
+      </div>
+      <div id="dst">
+        <pre>{{ .DstHTML }}</pre>
+      </div>
+    </div>
+    <script>
+      (function() {
+        function node(id) {
+          return id ? document.querySelector("[data-id='" + id + "']") : null;
+        }
+
+        function mark(className, ids) {
+          document.querySelectorAll("." + className).forEach(function(el) {
+            el.classList.remove(className);
+          });
+          ids.forEach(function(id) {
+            var el = node(id);
+            if (el) {
+              el.classList.add(className);
+            }
+          });
+        }
+
+        // highlight the innermost node under the cursor and its counterpart
+        document.querySelectorAll("pre").forEach(function(pre) {
+          pre.addEventListener("mouseover", function(e) {
+            var el = e.target.closest("[data-id]");
+            mark("hover", el ? [el.dataset.id, el.dataset.match] : []);
+          });
+          pre.addEventListener("mouseleave", function() {
+            mark("hover", []);
+          });
+          pre.addEventListener("click", function(e) {
+            var el = e.target.closest("[data-id]");
+            if (!el) {
+              return;
+            }
+            mark("selected", [el.dataset.id, el.dataset.match]);
+            var other = node(el.dataset.match);
+            if (other) {
+              other.scrollIntoView({ block: "center" });
+            }
+          });
+        });
+
+        document.querySelectorAll("#actions li").forEach(function(li) {
+          li.addEventListener("click", function() {
+            mark("selected", [li.dataset.target, li.dataset.other]);
+            [li.dataset.target, li.dataset.other].forEach(function(id) {
+              var el = node(id);
+              if (el) {
+                el.scrollIntoView({ block: "center" });
+              }
+            });
+          });
+        });
+
+        // connect moved nodes with arrows
+        var files = document.getElementById("files");
+        var svg = document.getElementById("arrows");
+        function drawArrows() {
+          svg.querySelectorAll("path.arrow").forEach(function(p) {
+            p.remove();
+          });
+          svg.setAttribute("width", files.scrollWidth);
+          svg.setAttribute("height", files.scrollHeight);
+
+          var box = files.getBoundingClientRect();
+          document.querySelectorAll("#src .mv").forEach(function(from) {
+            var to = node(from.dataset.match);
+            if (!to) {
+              return;
+            }
+            var a = from.getBoundingClientRect();
+            var b = to.getBoundingClientRect();
+            var x1 = a.right - box.left + files.scrollLeft;
+            var y1 = a.top + 8 - box.top + files.scrollTop;
+            var x2 = b.left - box.left + files.scrollLeft;
+            var y2 = b.top + 8 - box.top + files.scrollTop;
+            var dx = Math.max(40, (x2 - x1) / 2);
+
+            var p = document.createElementNS("http://www.w3.org/2000/svg", "path");
+            p.setAttribute("class", "arrow");
+            p.setAttribute("d", "M" + x1 + "," + y1 + " C" + (x1 + dx) + "," + y1 + " " + (x2 - dx) + "," + y2 + " " + x2 + "," + y2);
+            p.setAttribute("fill", "none");
+            p.setAttribute("stroke", "#888");
+            p.setAttribute("marker-end", "url(#head)");
+            svg.appendChild(p);
+          });
+        }
+        window.addEventListener("resize", drawArrows);
+        drawArrows();
+      })();
+    </script>
+  </body>
+</html>
+`
@@ -1,42 +1,52 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
+	"html"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"text/template"
 
 	"github.com/smacker/gum"
+	"github.com/smacker/gum/data"
 	"github.com/smacker/gum/golang"
+	"github.com/smacker/gum/tsitter"
 	"github.com/smacker/gum/uast"
-	bblfshUAST "gopkg.in/bblfsh/sdk.v2/uast"
+	"github.com/smacker/gum/unified"
+	"github.com/smacker/gum/xml"
 
 	flags "github.com/jessevdk/go-flags"
 	bblfsh "gopkg.in/bblfsh/client-go.v2"
-	"gopkg.in/bblfsh/sdk.v2/uast/nodes"
 )
 
+type parserOptions struct {
+	Parser string `short:"p" long:"parser" default:"bblfsh" choice:"bblfsh" choice:"uast" choice:"go" choice:"go-types" choice:"tree-sitter" choice:"gumtree" choice:"json" choice:"yaml" choice:"toml" choice:"xml" choice:"html" description:"go-types type-checks files read from disk with the rest of their package, contents from git or requests alone"`
+	Roles  string `long:"roles" default:"none" choice:"none" choice:"type" choice:"label" choice:"attr" description:"keep roles of bblfsh nodes in types, labels or attributes"`
+}
+
 type parseOptions struct {
-	Parser string `short:"p" long:"parser" default:"bblfsh" choice:"bblfsh" choice:"go"`
-	Args   struct {
+	parserOptions
+	Args struct {
 		Src string
 		Dst string
 	} `positional-args:"yes" required:"yes"`
 }
 
 func (p *parseOptions) parse() (*gum.Tree, *gum.Tree, error) {
-	src, err := parseFile(p.Args.Src, p.Parser)
+	src, err := parseFile(p.Args.Src, p.parserOptions)
 	if err != nil {
 		return nil, nil, err
 	}
-	dst, err := parseFile(p.Args.Dst, p.Parser)
+	dst, err := parseFile(p.Args.Dst, p.parserOptions)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -44,16 +54,66 @@ func (p *parseOptions) parse() (*gum.Tree, *gum.Tree, error) {
 	return src, dst, nil
 }
 
-func parseFile(path string, parserName string) (*gum.Tree, error) {
-	switch parserName {
+func parseFile(path string, opts parserOptions) (*gum.Tree, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Parser == "go-types" {
+		return parseGoPackageFile(path, b)
+	}
+
+	return parseContent(path, b, opts)
+}
+
+// parseGoPackageFile type-checks the file together with other files
+// of its package in the same directory to resolve references between them
+func parseGoPackageFile(path string, content []byte) (*gum.Tree, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
+	}
+
+	files := []*ast.File{f}
+	siblings, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings {
+		if filepath.Clean(sibling) == filepath.Clean(path) {
+			continue
+		}
+		// broken files and files of other packages, like external tests, are skipped
+		sf, err := parser.ParseFile(fset, sibling, nil, 0)
+		if err != nil || sf.Name.Name != f.Name.Name {
+			continue
+		}
+		files = append(files, sf)
+	}
+
+	return golang.ToTypedTree(f, golang.TypeCheck(fset, files)), nil
+}
+
+// parseContent parses content of a file, path is used only to detect the language
+func parseContent(path string, content []byte, opts parserOptions) (*gum.Tree, error) {
+	switch opts.Parser {
 	case "go":
-		b, err := ioutil.ReadFile(path)
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
 		}
-		fset := token.NewFileSet()
-		f, err := parser.ParseFile(fset, "", string(b), parser.ParseComments)
 		return golang.ToTree(f), nil
+	case "go-types":
+		// only the content itself is type-checked, references to other files of the package
+		// are resolved only for files read from disk by parseFile
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
+		}
+		return golang.ToTypedTree(f, golang.TypeCheck(fset, []*ast.File{f})), nil
 	case "bblfsh":
 		client, err := bblfsh.NewClient("0.0.0.0:9432")
 		if err != nil {
@@ -62,14 +122,75 @@ func parseFile(path string, parserName string) (*gum.Tree, error) {
 		res, _, err := client.
 			NewParseRequest().
 			Mode(bblfsh.Annotated).
-			ReadFile(path).
+			Filename(filepath.Base(path)).
+			Content(string(content)).
 			UAST()
 		if err != nil {
 			return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
 		}
-		return uast.ToTree(res), nil
+		return uast.ToTreeWithRoles(res, uastRoles(opts.Roles)), nil
+	case "uast":
+		// nodes exported from bblfsh, no server is required
+		n, err := uast.Unmarshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("can't read uast from the file %s: %s", path, err)
+		}
+		return uast.ToTreeWithRoles(n, uastRoles(opts.Roles)), nil
+	case "tree-sitter":
+		lang := tsitter.DetectLanguage(path)
+		if lang == nil {
+			return nil, fmt.Errorf("can't detect language of the file %s", path)
+		}
+		return tsitter.Parse(content, lang)
+	case "gumtree":
+		// trees exported by GumTree in json or xml format
+		if bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+			return gum.ReadTreeXML(bytes.NewReader(content))
+		}
+		return gum.ReadTreeJSON(bytes.NewReader(content))
+	case "json", "yaml", "toml":
+		t, err := dataParsers[opts.Parser](content)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
+		}
+		return t, nil
+	case "xml", "html":
+		parse := xml.Parse
+		if opts.Parser == "html" {
+			parse = xml.ParseHTML
+		}
+		t, err := parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse the file %s: %s", path, err)
+		}
+		return t, nil
 	default:
-		return nil, fmt.Errorf("unknown parser %s", parserName)
+		return nil, fmt.Errorf("unknown parser %s", opts.Parser)
+	}
+}
+
+var dataParsers = map[string]func([]byte) (*gum.Tree, error){
+	"json": data.ParseJSON,
+	"yaml": data.ParseYAML,
+	"toml": data.ParseTOML,
+}
+
+// isDataParser returns true for parsers of data formats which describe changes by keys
+func isDataParser(parserName string) bool {
+	_, ok := dataParsers[parserName]
+	return ok
+}
+
+func uastRoles(roles string) uast.Roles {
+	switch roles {
+	case "type":
+		return uast.RolesInType
+	case "label":
+		return uast.RolesInLabel
+	case "attr":
+		return uast.RolesInAttrs
+	default:
+		return uast.NoRoles
 	}
 }
 
@@ -126,11 +247,7 @@ func (c *matchCommand) Execute(args []string) error {
 		}
 
 		fmt.Println("png file:", pngf.Name())
-		if runtime.GOOS == "darwin" {
-			_ = exec.Command("open", pngf.Name()).Run()
-		} else if runtime.GOOS == "linux" {
-			_ = exec.Command("xdg-open", pngf.Name()).Run()
-		}
+		openFile(pngf.Name())
 
 		return nil
 	default:
@@ -203,89 +320,102 @@ func dotToPng(dotfile string, outfile string) error {
 
 type diffCommand struct {
 	parseOptions
+	termOptions
+	Mode      string `short:"m" long:"mode" default:"json" choice:"json" choice:"xml" choice:"text" choice:"term" choice:"unified" description:"output format, term prints colored source, unified prints textual diff"`
+	Recursive bool   `short:"r" long:"recursive" description:"compare directories"`
 }
 
 func (c *diffCommand) Execute(args []string) error {
+	if c.Recursive {
+		return c.executeRecursive()
+	}
+
 	src, dst, err := c.parse()
 	if err != nil {
 		return err
 	}
 
-	mappings := gum.Match(src, dst)
-	actions := gum.Patch(src, dst, mappings)
-
-	matchers := make([]*jsonMatch, len(mappings))
-	for i, m := range mappings {
-		matchers[i] = &jsonMatch{Src: m[0].GetID(), Dst: m[1].GetID()}
-	}
-
-	jsonActions := make([]*jsonAction, len(actions))
-	for i, a := range actions {
-		parent := 0
-		if a.Parent != nil {
-			parent = a.Parent.GetID()
+	if c.Mode == "term" || c.Mode == "unified" {
+		srcb, err := ioutil.ReadFile(c.Args.Src)
+		if err != nil {
+			return err
 		}
-		jsonActions[i] = &jsonAction{
-			Action: typeToStr[a.Type],
-			Tree:   a.Node.GetID(),
-			Parent: parent,
-			At:     a.Pos,
-			Label:  a.Value,
+		dstb, err := ioutil.ReadFile(c.Args.Dst)
+		if err != nil {
+			return err
+		}
+		if c.Mode == "unified" {
+			return writeUnifiedDiff(os.Stdout, c.Args.Src, c.Args.Dst, srcb, dstb, src, dst, c.Context)
 		}
+		return writeTermDiff(os.Stdout, srcb, dstb, src, dst, c.termOptions)
 	}
 
-	b, err := json.MarshalIndent(struct {
-		Matches []*jsonMatch  `json:"matches"`
-		Actions []*jsonAction `json:"actions"`
-	}{matchers, jsonActions}, "", "  ")
-	if err != nil {
-		return err
-	}
-	fmt.Println(string(b))
+	mappings := gum.Match(src, dst)
+	actions := gum.Patch(src, dst, mappings)
 
-	return nil
+	switch c.Mode {
+	case "json":
+		return gum.NewDiffDocument(mappings, actions).WriteJSON(os.Stdout)
+	case "xml":
+		return gum.NewDiffDocument(mappings, actions).WriteXML(os.Stdout)
+	case "text":
+		if isDataParser(c.Parser) {
+			for _, a := range actions {
+				fmt.Println(data.Describe(a))
+			}
+			return nil
+		}
+		return gum.WriteTextDiff(os.Stdout, mappings, actions)
+	default:
+		return fmt.Errorf("unknown mode %s", c.Mode)
+	}
 }
 
-var typeToStr = map[gum.Operation]string{
-	gum.Delete:     "delete",
-	gum.DeleteTree: "delete-tree",
-	gum.Insert:     "insert",
-	gum.InsertTree: "insert-tree",
-	gum.Update:     "update",
-	gum.Move:       "move",
+// writeUnifiedDiff writes textual diff of the files with hunks following the changes of the trees
+func writeUnifiedDiff(w io.Writer, srcName, dstName string, srcb, dstb []byte, src, dst *gum.Tree, context int) error {
+	mappings := gum.Match(src, dst)
+	actions := gum.Patch(src, dst, mappings)
+	return unified.Write(w,
+		&unified.File{Name: srcName, Content: srcb, Tree: src},
+		&unified.File{Name: dstName, Content: dstb, Tree: dst},
+		mappings, actions, context)
 }
 
-type jsonMatch struct {
-	Src int `json:"src"`
-	Dst int `json:"dest"`
+type parseCommand struct {
+	parserOptions
+	Mode string `short:"m" long:"mode" default:"json" choice:"json" choice:"xml" description:"output format compatible with GumTree"`
+	Args struct {
+		File string
+	} `positional-args:"yes" required:"yes"`
 }
 
-type jsonAction struct {
-	Action string `json:"action"`
-	Tree   int    `json:"tree"`
-	Parent int    `json:"parent,omitempty"`
-	At     int    `json:"at,omitempty"`
-	Label  string `json:"label,omitempty"`
+func (c *parseCommand) Execute(args []string) error {
+	t, err := parseFile(c.Args.File, c.parserOptions)
+	if err != nil {
+		return err
+	}
+
+	switch c.Mode {
+	case "json":
+		return gum.WriteTreeJSON(os.Stdout, t)
+	case "xml":
+		return gum.WriteTreeXML(os.Stdout, t)
+	default:
+		return fmt.Errorf("unknown mode %s", c.Mode)
+	}
 }
 
 type webCommand struct {
 	parseOptions
+	Interactive bool `long:"interactive" description:"link mapped nodes and show the list of actions"`
 }
 
 func (c *webCommand) Execute(args []string) error {
-	if c.Parser != "bblfsh" {
-		return fmt.Errorf("only bblfsh driver supports webdiff for now")
-	}
-
 	src, dst, err := c.parse()
 	if err != nil {
 		return err
 	}
 
-	mappings := gum.Match(src, dst)
-	actions := gum.Patch(src, dst, mappings)
-	srcGroups, dstGroups := c.treeGroups(actions, mappings)
-
 	srcb, err := ioutil.ReadFile(c.Args.Src)
 	if err != nil {
 		return err
@@ -295,23 +425,16 @@ func (c *webCommand) Execute(args []string) error {
 		return err
 	}
 
-	t, err := template.New("webpage").Parse(tpl)
-	if err != nil {
-		return err
-	}
-
 	htmlf, err := ioutil.TempFile("", "gum_diff_*.html")
 	if err != nil {
 		return err
 	}
 
-	if err := t.Execute(htmlf, struct {
-		SrcHTML string
-		DstHTML string
-	}{
-		SrcHTML: c.genHTML(srcb, c.srcTags(src, srcGroups)),
-		DstHTML: c.genHTML(dstb, c.dstTags(dst, dstGroups)),
-	}); err != nil {
+	write := writeWebdiff
+	if c.Interactive {
+		write = writeInteractiveWebdiff
+	}
+	if err := write(htmlf, srcb, dstb, src, dst); err != nil {
 		return err
 	}
 
@@ -320,54 +443,133 @@ func (c *webCommand) Execute(args []string) error {
 	}
 
 	fmt.Println("html file:", htmlf.Name())
+	openFile(htmlf.Name())
+
+	return nil
+}
+
+// writeWebdiff writes html page with highlighted differences between src and dst
+func writeWebdiff(w io.Writer, srcb, dstb []byte, src, dst *gum.Tree) error {
+	mappings := gum.Match(src, dst)
+	actions := gum.Patch(src, dst, mappings)
+	srcGroups, dstGroups := treeGroups(actions, mappings)
+
+	t, err := template.New("webpage").Parse(tpl)
+	if err != nil {
+		return err
+	}
+
+	srcSpans, dstSpans := srcTags(src, srcGroups), dstTags(dst, dstGroups)
+	dels, inss := labelDiffRanges(actions, mappings, srcb, dstb)
+	for _, r := range dels {
+		srcSpans.add(r[0], r[1], "del")
+	}
+	for _, r := range inss {
+		dstSpans.add(r[0], r[1], "add")
+	}
+
+	return t.Execute(w, struct {
+		SrcHTML string
+		DstHTML string
+	}{
+		SrcHTML: genHTML(srcb, srcSpans),
+		DstHTML: genHTML(dstb, dstSpans),
+	})
+}
+
+// openFile opens the file in the default application if it's supported by OS
+func openFile(name string) {
 	if runtime.GOOS == "darwin" {
-		_ = exec.Command("open", htmlf.Name()).Run()
+		_ = exec.Command("open", name).Run()
 	} else if runtime.GOOS == "linux" {
-		_ = exec.Command("xdg-open", htmlf.Name()).Run()
+		_ = exec.Command("xdg-open", name).Run()
 	}
+}
 
-	return nil
+// groups of changed nodes, they are css classes of webdiff and colors of term mode
+var changeGroups = map[gum.Change]string{
+	gum.Inserted: "add",
+	gum.Deleted:  "del",
+	gum.Updated:  "upd",
+	gum.Moved:    "mv",
 }
 
-func (c *webCommand) treeGroups(actions []*gum.Action, mappings []gum.Mapping) (map[string][]*gum.Tree, map[string][]*gum.Tree) {
-	srcGroups := map[string][]*gum.Tree{
-		"mv":  []*gum.Tree{},
-		"del": []*gum.Tree{},
-		"upd": []*gum.Tree{},
-	}
-	dstGroups := map[string][]*gum.Tree{
-		"add": []*gum.Tree{},
-		"mv":  []*gum.Tree{},
-		"del": []*gum.Tree{},
-		"upd": []*gum.Tree{},
+func treeGroups(actions []*gum.Action, mappings []gum.Mapping) (map[string][]*gum.Tree, map[string][]*gum.Tree) {
+	srcChanges, dstChanges := gum.Changes(actions, mappings)
+	groups := func(changes map[*gum.Tree]gum.Change) map[string][]*gum.Tree {
+		g := map[string][]*gum.Tree{
+			"add": []*gum.Tree{},
+			"mv":  []*gum.Tree{},
+			"del": []*gum.Tree{},
+			"upd": []*gum.Tree{},
+		}
+		for t, c := range changes {
+			g[changeGroups[c]] = append(g[changeGroups[c]], t)
+		}
+		return g
 	}
 
+	return groups(srcChanges), groups(dstChanges)
+}
+
+// labelDiffRanges returns ranges of deleted words in src and inserted words in dst
+// for updated leaves that changed only partially and have labels in the source
+func labelDiffRanges(actions []*gum.Action, mappings []gum.Mapping, srcb, dstb []byte) ([][2]int, [][2]int) {
+	var dels, inss [][2]int
 	for _, a := range actions {
-		switch a.Type {
-		case gum.Insert:
-			dstGroups["add"] = append(dstGroups["add"], a.Node)
-		case gum.InsertTree:
-			dstGroups["add"] = append(dstGroups["add"], a.Node)
-			for _, n := range gum.PreOrder(a.Node) {
-				dstGroups["add"] = append(dstGroups["add"], n)
-			}
-		case gum.DeleteTree:
-			srcGroups["del"] = append(srcGroups["del"], a.Node)
-			for _, n := range gum.PreOrder(a.Node) {
-				srcGroups["del"] = append(srcGroups["del"], n)
+		if a.Type != gum.Update || len(a.Node.Children) > 0 {
+			continue
+		}
+		dst := getDst(mappings, a.Node)
+		if dst == nil {
+			continue
+		}
+		i, ok := labelOffset(srcb, a.Node)
+		if !ok {
+			continue
+		}
+		j, ok := labelOffset(dstb, dst)
+		if !ok {
+			continue
+		}
+
+		segments := a.Segments()
+		partial := false
+		for _, s := range segments {
+			partial = partial || s.Type == gum.SegmentEqual
+		}
+		if !partial {
+			continue
+		}
+		for _, s := range segments {
+			n := len(s.Text)
+			switch s.Type {
+			case gum.SegmentEqual:
+				i += n
+				j += n
+			case gum.SegmentDelete:
+				dels = append(dels, [2]int{i, i + n})
+				i += n
+			case gum.SegmentInsert:
+				inss = append(inss, [2]int{j, j + n})
+				j += n
 			}
-		case gum.Delete:
-			srcGroups["del"] = append(srcGroups["del"], a.Node)
-		case gum.Update:
-			srcGroups["upd"] = append(srcGroups["upd"], a.Node)
-			dstGroups["upd"] = append(dstGroups["upd"], getDst(mappings, a.Node))
-		case gum.Move:
-			srcGroups["mv"] = append(srcGroups["mv"], a.Node)
-			dstGroups["mv"] = append(dstGroups["mv"], getDst(mappings, a.Node))
 		}
 	}
 
-	return srcGroups, dstGroups
+	return dels, inss
+}
+
+// labelOffset returns the offset of the label of the node in the source
+func labelOffset(content []byte, t *gum.Tree) (int, bool) {
+	if t.Length == 0 || t.Value == "" || t.End() > len(content) {
+		return 0, false
+	}
+	i := bytes.Index(content[t.Pos:t.End()], []byte(t.Value))
+	if i < 0 {
+		return 0, false
+	}
+	return t.Pos + i, true
 }
 
 func getDst(mappings []gum.Mapping, t *gum.Tree) *gum.Tree {
@@ -380,13 +582,14 @@ func getDst(mappings []gum.Mapping, t *gum.Tree) *gum.Tree {
 	return nil
 }
 
-func (c *webCommand) srcTags(src *gum.Tree, treeGroups map[string][]*gum.Tree) *tags {
+func srcTags(src *gum.Tree, treeGroups map[string][]*gum.Tree) *tags {
 	tags := newTags()
 	for _, t := range gum.PreOrder(src) {
-		n := t.Meta.(nodes.Node)
-		pos := bblfshUAST.PositionsOf(n)
-		start := int(pos["start"].Offset)
-		end := int(pos["end"].Offset)
+		// the parser doesn't provide position of the node
+		if t.Length == 0 {
+			continue
+		}
+		start, end := t.Pos, t.End()
 
 		switch true {
 		case inGroup(treeGroups["mv"], t):
@@ -401,13 +604,14 @@ func (c *webCommand) srcTags(src *gum.Tree, treeGroups map[string][]*gum.Tree) *
 	return tags
 }
 
-func (c *webCommand) dstTags(dst *gum.Tree, treeGroups map[string][]*gum.Tree) *tags {
+func dstTags(dst *gum.Tree, treeGroups map[string][]*gum.Tree) *tags {
 	tags := newTags()
 	for _, t := range gum.PreOrder(dst) {
-		n := t.Meta.(nodes.Node)
-		pos := bblfshUAST.PositionsOf(n)
-		start := int(pos["start"].Offset)
-		end := int(pos["end"].Offset)
+		// the parser doesn't provide position of the node
+		if t.Length == 0 {
+			continue
+		}
+		start, end := t.Pos, t.End()
 
 		switch true {
 		case inGroup(treeGroups["mv"], t):
@@ -422,21 +626,27 @@ func (c *webCommand) dstTags(dst *gum.Tree, treeGroups map[string][]*gum.Tree) *
 	return tags
 }
 
-func (c *webCommand) genHTML(text []byte, tags *tags) string {
+func genHTML(text []byte, tags *tags) string {
 	var htmlb []byte
 	var i int
 	for _, ch := range text {
-		if v, ok := tags.starts[i]; ok {
-			for _, action := range v {
-				htmlb = append(htmlb, []byte("<span class='"+action+"'>")...)
-			}
-		}
+		// close spans before opening new ones at the same position
 		if v, ok := tags.ends[i]; ok {
 			for j := 0; j < v; j++ {
 				htmlb = append(htmlb, []byte("</span>")...)
 			}
 		}
-		htmlb = append(htmlb, ch)
+		if v, ok := tags.starts[i]; ok {
+			for _, tag := range v {
+				htmlb = append(htmlb, tag...)
+			}
+		}
+		switch ch {
+		case '<', '>', '&', '\'', '"':
+			htmlb = append(htmlb, html.EscapeString(string(ch))...)
+		default:
+			htmlb = append(htmlb, ch)
+		}
 		i++
 	}
 	if v, ok := tags.ends[i]; ok {
@@ -449,6 +659,7 @@ func (c *webCommand) genHTML(text []byte, tags *tags) string {
 }
 
 type tags struct {
+	// opening tags of spans
 	starts map[int][]string
 	ends   map[int]int
 }
@@ -461,7 +672,11 @@ func newTags() *tags {
 }
 
 func (ts *tags) add(start, end int, v string) {
-	ts.starts[start] = append(ts.starts[start], v)
+	ts.addSpan(start, end, "<span class='"+v+"'>")
+}
+
+func (ts *tags) addSpan(start, end int, tag string) {
+	ts.starts[start] = append(ts.starts[start], tag)
 	ts.ends[end]++
 }
 
@@ -492,6 +707,12 @@ const tpl = `
 	  .upd {
         background: #ffffd8;
 	  }
+      .upd .del {
+        background: #fdb8c0;
+      }
+      .upd .add {
+        background: #acf2bd;
+      }
       .mv {
         background: #efefef;
       }
@@ -513,9 +734,17 @@ const tpl = `
 func main() {
 	parser := flags.NewNamedParser("gum", flags.Default)
 
+	parser.AddCommand("parse", "parse a file and display the tree", "", &parseCommand{})
 	parser.AddCommand("match", "parse and display matched nodes", "", &matchCommand{})
 	parser.AddCommand("diff", "parse and display actions", "", &diffCommand{})
 	parser.AddCommand("webdiff", "parse and show web diff", "", &webCommand{})
+	parser.AddCommand("git", "parse and display actions for files changed between git revisions", "", &gitCommand{})
+	parser.AddCommand("merge-driver", "merge versions of a file as git merge driver", "", &mergeDriverCommand{})
+	parser.AddCommand("serve", "run http server to browse diffs", "", &serveCommand{})
+	parser.AddCommand("clones", "find code clones in go files of a directory", "", &clonesCommand{})
+	parser.AddCommand("apicheck", "report changes of exported api between two versions of a go package", "", &apicheckCommand{})
+	parser.AddCommand("learn", "learn a rewrite template from pairs of files before and after a change", "", &learnCommand{})
+	parser.AddCommand("rewrite", "replace occurrences of a learned template in files", "", &rewriteCommand{})
 
 	_, err := parser.Parse()
 	if err != nil {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentTreeSitter(t *testing.T) {
+	tree, err := parseContent("foo/bar.py", []byte("def foo():\n    return 1\n"), parserOptions{Parser: "tree-sitter"})
+	require.NoError(t, err)
+	assert.Equal(t, "module", tree.Type)
+
+	_, err = parseContent("README", []byte("readme"), parserOptions{Parser: "tree-sitter"})
+	assert.Error(t, err)
+
+	assert.True(t, isSupportedFile("main.rs", "tree-sitter"))
+	assert.False(t, isSupportedFile("README", "tree-sitter"))
+}
+
+func TestParseContentGoTypes(t *testing.T) {
+	tree, err := parseContent("main.go", []byte("package main\n\nfunc main() {\n\tx := 1\n\t_ = x\n}\n"), parserOptions{Parser: "go-types"})
+	require.NoError(t, err)
+
+	var refs []string
+	for _, n := range gum.PreOrder(tree) {
+		if n.Value == "x" {
+			refs = append(refs, n.Ref)
+		}
+	}
+	require.Len(t, refs, 2)
+	assert.NotEmpty(t, refs[0])
+	assert.Equal(t, refs[0], refs[1])
+}
+
+func TestParseFileGoTypesPackage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc helper() {}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package a_test\n\nfunc helper() {}\n"), 0644))
+	path := filepath.Join(dir, "b.go")
+	require.NoError(t, ioutil.WriteFile(path, []byte("package a\n\nfunc f() {\n\thelper()\n}\n"), 0644))
+
+	tree, err := parseFile(path, parserOptions{Parser: "go-types"})
+	require.NoError(t, err)
+
+	var ref string
+	for _, n := range gum.PreOrder(tree) {
+		if n.Value == "helper" {
+			ref = n.Ref
+		}
+	}
+	// the function is declared in another file of the package
+	assert.NotEmpty(t, ref)
+}
+
+func TestParseContentUAST(t *testing.T) {
+	b, err := ioutil.ReadFile("../../uast/testdata/src.uast")
+	require.NoError(t, err)
+
+	tree, err := parseContent("src.uast", b, parserOptions{Parser: "uast"})
+	require.NoError(t, err)
+	assert.Equal(t, "CompilationUnit", tree.Type)
+
+	tree, err = parseContent("src.uast", b, parserOptions{Parser: "uast", Roles: "type"})
+	require.NoError(t, err)
+	assert.Equal(t, "CompilationUnit[File]", tree.Type)
+
+	_, err = parseContent("src.uast", []byte("{ broken"), parserOptions{Parser: "uast"})
+	assert.Error(t, err)
+
+	tree, err = parseContent("src.uast", b, parserOptions{Parser: "uast", Roles: "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "CompilationUnit", tree.Type)
+}
+
+func TestParseContentData(t *testing.T) {
+	tree, err := parseContent("deployment.yaml", []byte("spec:\n  replicas: 2\n"), parserOptions{Parser: "yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, "Object", tree.Type)
+	assert.Equal(t, "spec", tree.Children[0].Type)
+
+	_, err = parseContent("config.json", []byte("{"), parserOptions{Parser: "json"})
+	assert.Error(t, err)
+
+	assert.True(t, isSupportedFile("config.toml", "toml"))
+	assert.False(t, isSupportedFile("config.yaml", "toml"))
+	assert.True(t, isDataParser("yaml"))
+	assert.False(t, isDataParser("go"))
+}
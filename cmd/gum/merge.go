@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/smacker/gum/merge"
+)
+
+// mergeDriverCommand implements git merge driver
+//
+// git config merge.gum.driver "gum merge-driver -p go %O %A %B %P"
+type mergeDriverCommand struct {
+	parserOptions
+	Args struct {
+		Base   string
+		Ours   string
+		Theirs string
+		Path   string
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *mergeDriverCommand) Execute(args []string) error {
+	// positions of imported trees refer to their original sources, not to the merged files
+	if c.Parser == "gumtree" || c.Parser == "uast" {
+		return fmt.Errorf("parser %s can't be used for merge, positions of its nodes don't refer to the file", c.Parser)
+	}
+
+	base, err := c.version(c.Args.Base)
+	if err != nil {
+		return c.fallback(err)
+	}
+	ours, err := c.version(c.Args.Ours)
+	if err != nil {
+		return c.fallback(err)
+	}
+	theirs, err := c.version(c.Args.Theirs)
+	if err != nil {
+		return c.fallback(err)
+	}
+
+	res := merge.Merge(base, ours, theirs)
+	content := res.Content
+	if len(res.Conflicts) == 0 && (c.Parser == "go" || c.Parser == "go-types") {
+		content, err = format.Source(content)
+		if err != nil {
+			return c.fallback(fmt.Errorf("can't print merged file: %s", err))
+		}
+	}
+
+	if err := ioutil.WriteFile(c.Args.Ours, content, 0644); err != nil {
+		return err
+	}
+	if len(res.Conflicts) > 0 {
+		return fmt.Errorf("%d conflicts in %s", len(res.Conflicts), c.Args.Path)
+	}
+
+	return nil
+}
+
+func (c *mergeDriverCommand) version(path string) (*merge.Version, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseContent(c.Args.Path, b, c.parserOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &merge.Version{Content: b, Tree: t}
+	if err := v.CheckPositions(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return v, nil
+}
+
+// fallback to textual merge if the files can't be merged structurally
+func (c *mergeDriverCommand) fallback(reason error) error {
+	fmt.Fprintf(os.Stderr, "structural merge of %s failed: %s\n", c.Args.Path, reason)
+
+	cmd := exec.Command("git", "merge-file",
+		"-L", "ours", "-L", "base", "-L", "theirs",
+		c.Args.Ours, c.Args.Base, c.Args.Theirs)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("conflicts in %s", c.Args.Path)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeDriver(t *testing.T, parser, name, base, ours, theirs string) (string, error) {
+	dir := t.TempDir()
+	c := &mergeDriverCommand{}
+	c.Parser = parser
+	c.Args.Path = name
+	for path, content := range map[*string]string{&c.Args.Base: base, &c.Args.Ours: ours, &c.Args.Theirs: theirs} {
+		f, err := ioutil.TempFile(dir, "*_"+name)
+		require.NoError(t, err)
+		_, err = f.WriteString(content)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		*path = f.Name()
+	}
+
+	err := c.Execute(nil)
+	b, rerr := ioutil.ReadFile(c.Args.Ours)
+	require.NoError(t, rerr)
+	return string(b), err
+}
+
+func TestMergeDriver(t *testing.T) {
+	res, err := mergeDriver(t, "go", "foo.go", gitSrc, gitSrc, gitDst)
+	require.NoError(t, err)
+	assert.Equal(t, gitDst, res)
+}
+
+func TestMergeDriverPositions(t *testing.T) {
+	// trees without positions are merged as text
+	res, err := mergeDriver(t, "yaml", "a.yaml", "# a\n", "# a\n", "# a\nb: 1\n")
+	require.NoError(t, err)
+	assert.Equal(t, "# a\nb: 1\n", res)
+
+	// positions of imported trees refer to other files
+	tree := `{"root": {"type": "File", "pos": "0", "length": "1000", "children": []}}`
+	res, err = mergeDriver(t, "gumtree", "a.json", tree, tree, tree+"\n")
+	assert.Error(t, err)
+	assert.Equal(t, tree, res)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/dirdiff"
+	"github.com/smacker/gum/tsitter"
+)
+
+func (c *diffCommand) executeRecursive() error {
+	if c.Mode != "json" && c.Mode != "text" {
+		return fmt.Errorf("mode %s isn't supported with --recursive, use json or text", c.Mode)
+	}
+
+	res, err := compareDirs(c.Args.Src, c.Args.Dst, c.parserOptions)
+	if err != nil {
+		return err
+	}
+
+	if c.Mode == "text" {
+		writeDirTextDiff(os.Stdout, res)
+		return nil
+	}
+
+	b, err := json.MarshalIndent(newJSONDirDiff(res), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+
+	return nil
+}
+
+// compareDirs compares all supported files of two directories
+func compareDirs(srcDir, dstDir string, opts parserOptions) (*dirdiff.Result, error) {
+	src, err := parseDir(srcDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseDir(dstDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return dirdiff.Compare(src, dst), nil
+}
+
+// diffDirs compares all supported files of two directories
+func diffDirs(srcDir, dstDir string, opts parserOptions) (*jsonDirDiff, error) {
+	res, err := compareDirs(srcDir, dstDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONDirDiff(res), nil
+}
+
+func newJSONDirDiff(res *dirdiff.Result) *jsonDirDiff {
+	d := &jsonDirDiff{Moves: make([]*jsonMove, len(res.Moves))}
+	for _, f := range res.Files {
+		// skip files without changes
+		if f.Src == f.Dst && len(f.Actions) == 0 {
+			continue
+		}
+
+		jf := &jsonFileDiff{Src: f.Src, Dst: f.Dst, Renamed: f.Renamed}
+		if f.SrcTree != nil && f.DstTree != nil {
+			jf.DiffDocument = gum.NewDiffDocument(f.Mappings, f.Actions)
+		}
+		d.Files = append(d.Files, jf)
+	}
+
+	for i, m := range res.Moves {
+		d.Moves[i] = &jsonMove{
+			SrcFile: m.SrcFile,
+			Src:     m.Src.GetID(),
+			DstFile: m.DstFile,
+			Dst:     m.Dst.GetID(),
+			Type:    m.Src.Type,
+		}
+	}
+
+	return d
+}
+
+// writeDirTextDiff writes actions of the changed files in the format of the git command
+// followed by declarations moved between files
+func writeDirTextDiff(w io.Writer, res *dirdiff.Result) {
+	for _, f := range res.Files {
+		if f.Src == f.Dst && len(f.Actions) == 0 {
+			continue
+		}
+		writeTextDiff(w, &gitFileDiff{
+			Src:      f.Src,
+			Dst:      f.Dst,
+			SrcTree:  f.SrcTree,
+			DstTree:  f.DstTree,
+			Mappings: f.Mappings,
+			Actions:  f.Actions,
+		})
+	}
+
+	for _, m := range res.Moves {
+		fmt.Fprintf(w, "moved %s(%d) from %s to %s(%d)\n", m.Src.Type, m.Src.GetID(), m.SrcFile, m.DstFile, m.Dst.GetID())
+	}
+}
+
+// parseDir parses all supported files in the directory except symlinks
+// and returns trees keyed by paths relative to the directory
+func parseDir(dir string, opts parserOptions) (map[string]*gum.Tree, error) {
+	trees := make(map[string]*gum.Tree)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// symlinks may point outside of the directory
+		if info.Mode()&os.ModeSymlink != 0 || !isSupportedFile(path, opts.Parser) {
+			return nil
+		}
+
+		t, err := parseFile(path, opts)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		trees[filepath.ToSlash(rel)] = t
+
+		return nil
+	})
+
+	return trees, err
+}
+
+func isSupportedFile(path string, parserName string) bool {
+	switch parserName {
+	case "go", "go-types":
+		return filepath.Ext(path) == ".go"
+	case "tree-sitter":
+		return tsitter.DetectLanguage(path) != nil
+	case "json":
+		return filepath.Ext(path) == ".json"
+	case "yaml":
+		return filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml"
+	case "toml":
+		return filepath.Ext(path) == ".toml"
+	case "xml":
+		return filepath.Ext(path) == ".xml" || filepath.Ext(path) == ".svg"
+	case "html":
+		return filepath.Ext(path) == ".html" || filepath.Ext(path) == ".htm"
+	default:
+		return true
+	}
+}
+
+type jsonDirDiff struct {
+	Files []*jsonFileDiff `json:"files"`
+	Moves []*jsonMove     `json:"moves"`
+}
+
+type jsonFileDiff struct {
+	Src     string `json:"src,omitempty"`
+	Dst     string `json:"dst,omitempty"`
+	Renamed bool   `json:"renamed,omitempty"`
+	*gum.DiffDocument
+}
+
+type jsonMove struct {
+	SrcFile string `json:"srcFile"`
+	Src     int    `json:"src"`
+	DstFile string `json:"dstFile"`
+	Dst     int    `json:"dest"`
+	Type    string `json:"type"`
+}
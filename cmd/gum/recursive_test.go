@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffRecursiveText(t *testing.T) {
+	srcDir, dstDir := t.TempDir(), t.TempDir()
+	write := func(dir, name, content string) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write(srcDir, "foo.go", gitSrc)
+	write(dstDir, "foo.go", gitDst)
+	write(srcDir, "same.go", "package foo\n")
+	write(dstDir, "same.go", "package foo\n")
+
+	res, err := compareDirs(srcDir, dstDir, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	writeDirTextDiff(&out, res)
+	assert.Equal(t, "diff foo.go\nupdate: BasicLit@@1; value: 2\n", out.String())
+
+	c := &diffCommand{Mode: "term", Recursive: true}
+	c.Parser = "go"
+	c.Args.Src, c.Args.Dst = srcDir, dstDir
+	assert.EqualError(t, c.Execute(nil), "mode term isn't supported with --recursive, use json or text")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+
+	"github.com/smacker/gum/rewrite"
+)
+
+// learnCommand generalizes changes of pairs of files into a rewrite template
+type learnCommand struct {
+	parserOptions
+	Output string `short:"o" long:"output" description:"file to write the template to instead of stdout"`
+	Args   struct {
+		Files []string `positional-arg-name:"src dst" required:"2"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *learnCommand) Execute(args []string) error {
+	if len(c.Args.Files)%2 != 0 {
+		return fmt.Errorf("examples must be pairs of src and dst files")
+	}
+
+	var examples []*rewrite.Example
+	for i := 0; i < len(c.Args.Files); i += 2 {
+		src, err := parseFile(c.Args.Files[i], c.parserOptions)
+		if err != nil {
+			return err
+		}
+		dstb, err := ioutil.ReadFile(c.Args.Files[i+1])
+		if err != nil {
+			return err
+		}
+		dst, err := parseContent(c.Args.Files[i+1], dstb, c.parserOptions)
+		if err != nil {
+			return err
+		}
+		examples = append(examples, &rewrite.Example{Src: src, Dst: dst, DstSource: dstb})
+	}
+
+	t, err := rewrite.Learn(examples...)
+	if err != nil {
+		return err
+	}
+
+	if c.Output == "" {
+		return t.Write(os.Stdout)
+	}
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return err
+	}
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewriteCommand replaces occurrences of a learned template in files
+type rewriteCommand struct {
+	parserOptions
+	Template string `short:"t" long:"template" required:"yes" description:"template written by learn"`
+	Write    bool   `short:"w" long:"write" description:"write result to the files instead of stdout"`
+	Args     struct {
+		Files []string `required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (c *rewriteCommand) Execute(args []string) error {
+	f, err := os.Open(c.Template)
+	if err != nil {
+		return err
+	}
+	t, err := rewrite.ReadTemplate(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("can't read the template %s: %s", c.Template, err)
+	}
+
+	for _, path := range c.Args.Files {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tree, err := parseContent(path, b, c.parserOptions)
+		if err != nil {
+			return err
+		}
+		res, n, err := t.RewriteSource(b, tree)
+		if err != nil {
+			return fmt.Errorf("can't rewrite the file %s: %s", path, err)
+		}
+		// text of the template keeps indentation of the example
+		if n > 0 && (c.Parser == "go" || c.Parser == "go-types") {
+			if formatted, err := format.Source(res); err == nil {
+				res = formatted
+			}
+		}
+
+		if !c.Write {
+			os.Stdout.Write(res)
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, res, info.Mode()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d rewritten\n", path, n)
+	}
+
+	return nil
+}
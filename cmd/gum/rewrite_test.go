@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLearnAndRewrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gum_rewrite_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	write := func(name, content string) {
+		require.NoError(t, ioutil.WriteFile(path(name), []byte(content), 0644))
+	}
+	write("a_src.go", "package a\n\nfunc f() {\n\tuse(ioutil.ReadFile(name))\n}\n")
+	write("a_dst.go", "package a\n\nfunc f() {\n\tuse(os.ReadFile(name))\n}\n")
+	write("b_src.go", "package b\n\nvar data, err = ioutil.ReadFile(filepath.Join(dir, \"b\"))\n")
+	write("b_dst.go", "package b\n\nvar data, err = os.ReadFile(filepath.Join(dir, \"b\"))\n")
+	write("target.go", "package c\n\nfunc g() {\n\tb, _ := ioutil.ReadFile(os.Args[1])\n\tioutil.ReadDir(\".\")\n\t_ = b\n}\n")
+
+	learn := &learnCommand{Output: path("template.json")}
+	learn.Parser = "go"
+	learn.Args.Files = []string{path("a_src.go"), path("a_dst.go"), path("b_src.go"), path("b_dst.go")}
+	require.NoError(t, learn.Execute(nil))
+
+	rw := &rewriteCommand{Template: path("template.json"), Write: true}
+	rw.Parser = "go"
+	rw.Args.Files = []string{path("target.go")}
+	require.NoError(t, rw.Execute(nil))
+
+	b, err := ioutil.ReadFile(path("target.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package c\n\nfunc g() {\n\tb, _ := os.ReadFile(os.Args[1])\n\tioutil.ReadDir(\".\")\n\t_ = b\n}\n", string(b))
+
+	learn.Args.Files = learn.Args.Files[:3]
+	assert.Error(t, learn.Execute(nil))
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+// serveCommand runs http server to browse diffs
+type serveCommand struct {
+	parserOptions
+	Addr string `long:"addr" default:"127.0.0.1:8080" description:"address to listen on"`
+	Root string `long:"root" description:"directory to read srcPath and dstPath from, only contents are accepted without it"`
+}
+
+func (c *serveCommand) Execute(args []string) error {
+	fmt.Printf("listening on http://%s\n", c.Addr)
+	return http.ListenAndServe(c.Addr, newServer(c.parserOptions, c.Root))
+}
+
+type server struct {
+	// parser used if a request doesn't specify it
+	parser parserOptions
+	// root is the only directory files are read from, reading is disabled if empty
+	root string
+}
+
+// newServer returns handler with the form, webdiff pages and json api:
+//
+//	/           form to submit sources or paths
+//	/diff       interactive webdiff of files or list of changed files of directories
+//	/api/match  mappings in the same format as diff command
+//	/api/diff   mappings and actions in the same format as diff command
+func newServer(parser parserOptions, root string) http.Handler {
+	s := &server{parser: parser, root: root}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.index)
+	mux.HandleFunc("/diff", s.webdiff)
+	mux.HandleFunc("/api/match", s.apiMatch)
+	mux.HandleFunc("/api/diff", s.apiDiff)
+
+	return mux
+}
+
+// serveRequest is a pair of sources given by content or by paths relative to the root,
+// if content is given the path is used only to detect the language
+type serveRequest struct {
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	SrcPath string `json:"srcPath"`
+	DstPath string `json:"dstPath"`
+	Parser  string `json:"parser"`
+
+	// local paths of the files to read
+	srcFile string
+	dstFile string
+	opts    parserOptions
+}
+
+func (s *server) readRequest(r *http.Request) (*serveRequest, error) {
+	req := &serveRequest{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, fmt.Errorf("incorrect request: %s", err)
+		}
+	} else {
+		req.Src = r.FormValue("src")
+		req.Dst = r.FormValue("dst")
+		req.SrcPath = r.FormValue("srcPath")
+		req.DstPath = r.FormValue("dstPath")
+		req.Parser = r.FormValue("parser")
+	}
+
+	if req.Parser == "" {
+		req.Parser = s.parser.Parser
+	}
+	req.opts = s.parser
+	req.opts.Parser = req.Parser
+	if req.Src == "" && req.SrcPath == "" || req.Dst == "" && req.DstPath == "" {
+		return nil, fmt.Errorf("src and dst sources or paths are required")
+	}
+
+	var err error
+	if req.Src == "" {
+		if req.srcFile, err = s.localPath(req.SrcPath); err != nil {
+			return nil, err
+		}
+	}
+	if req.Dst == "" {
+		if req.dstFile, err = s.localPath(req.DstPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// localPath resolves the path of a request against the root
+// rejecting paths that point outside of it
+func (s *server) localPath(path string) (string, error) {
+	if s.root == "" {
+		return "", fmt.Errorf("reading of %s is disabled, send the content instead", path)
+	}
+
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(clean) || isParentPath(clean) {
+		return "", fmt.Errorf("path %s is outside of the root", path)
+	}
+
+	// symlinks inside of the root may point outside of it
+	root, err := filepath.EvalSymlinks(s.root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, clean))
+	if err != nil {
+		return "", fmt.Errorf("path %s can't be read", path)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || isParentPath(rel) {
+		return "", fmt.Errorf("path %s is outside of the root", path)
+	}
+
+	return resolved, nil
+}
+
+// isParentPath returns true if the clean relative path starts with ".."
+func isParentPath(path string) bool {
+	return path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+// isDirs returns true if the request compares two directories
+func (req *serveRequest) isDirs() bool {
+	if req.Src != "" || req.Dst != "" {
+		return false
+	}
+	return isDir(req.srcFile) && isDir(req.dstFile)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// load returns contents and trees of src and dst
+func (req *serveRequest) load() ([]byte, []byte, *gum.Tree, *gum.Tree, error) {
+	srcb, src, err := loadSource(req.Src, req.SrcPath, req.srcFile, "src", req.opts)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	dstb, dst, err := loadSource(req.Dst, req.DstPath, req.dstFile, "dst", req.opts)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return srcb, dstb, src, dst, nil
+}
+
+// loadSource reads the file only if the content is empty
+func loadSource(content, path, file, name string, opts parserOptions) ([]byte, *gum.Tree, error) {
+	b := []byte(content)
+	if content == "" {
+		var err error
+		if b, err = ioutil.ReadFile(file); err != nil {
+			return nil, nil, fmt.Errorf("can't read %s", path)
+		}
+	}
+	if path != "" {
+		name = path
+	}
+
+	t, err := parseContent(name, b, opts)
+	return b, t, err
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTpl.Execute(w, s.parser.Parser); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) webdiff(w http.ResponseWriter, r *http.Request) {
+	req, err := s.readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if req.isDirs() {
+		d, err := diffDirs(req.srcFile, req.dstFile, req.opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = dirTpl.Execute(w, struct {
+			*serveRequest
+			*jsonDirDiff
+		}{req, d})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	srcb, dstb, src, dst, err := req.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := writeInteractiveWebdiff(w, srcb, dstb, src, dst); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) apiMatch(w http.ResponseWriter, r *http.Request) {
+	req, err := s.readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, _, src, dst, err := req.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, struct {
+		Matches []*gum.DiffMatch `json:"matches"`
+	}{gum.NewDiffDocument(gum.Match(src, dst), nil).Matches})
+}
+
+func (s *server) apiDiff(w http.ResponseWriter, r *http.Request) {
+	req, err := s.readRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.isDirs() {
+		d, err := diffDirs(req.srcFile, req.dstFile, req.opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, d)
+		return
+	}
+
+	_, _, src, dst, err := req.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mappings := gum.Match(src, dst)
+	writeJSON(w, gum.NewDiffDocument(mappings, gum.Patch(src, dst, mappings)))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+var indexTpl = template.Must(template.New("index").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <title>Gum</title>
+    <style>
+      body {
+        font-family: sans-serif;
+        margin: 16px;
+      }
+      form > div {
+        display: flex;
+        margin-bottom: 8px;
+      }
+      form > div > * {
+        flex: 1;
+        margin-right: 8px;
+      }
+      textarea {
+        height: 40vh;
+        font-family: monospace;
+      }
+    </style>
+  </head>
+  <body>
+    <form method="post" action="/diff">
+      <div>
+        <input name="srcPath" placeholder="source path or file name" />
+        <input name="dstPath" placeholder="destination path or file name" />
+      </div>
+      <div>
+        <textarea name="src" placeholder="source (leave empty to read the path)"></textarea>
+        <textarea name="dst" placeholder="destination (leave empty to read the path)"></textarea>
+      </div>
+      <div>
+        <input name="parser" value="{{ . }}" />
+        <button type="submit">Diff</button>
+      </div>
+    </form>
+  </body>
+</html>
+`))
+
+var dirTpl = template.Must(template.New("dir").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8" />
+    <title>Gum: {{ .SrcPath }} → {{ .DstPath }}</title>
+  </head>
+  <body style="font-family: sans-serif;">
+    <h3>{{ .SrcPath }} → {{ .DstPath }}</h3>
+    <ul>
+      {{- $req := . }}
+      {{- range .Files }}
+      <li>
+        {{- if and .Src .Dst }}
+        <a href="/diff?srcPath={{ $req.SrcPath }}/{{ .Src }}&amp;dstPath={{ $req.DstPath }}/{{ .Dst }}&amp;parser={{ $req.Parser }}">
+          {{ .Src }}{{ if .Renamed }} → {{ .Dst }}{{ end }}</a>
+        {{- else if .Src }}
+        deleted {{ .Src }}
+        {{- else }}
+        added {{ .Dst }}
+        {{- end }}
+      </li>
+      {{- end }}
+    </ul>
+    {{- if .Moves }}
+    <h4>Moved between files</h4>
+    <ul>
+      {{- range .Moves }}
+      <li>{{ .Type }}: {{ .SrcFile }} → {{ .DstFile }}</li>
+      {{- end }}
+    </ul>
+    {{- end }}
+  </body>
+</html>
+`))
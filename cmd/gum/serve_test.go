@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeAPIDiff(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, ""))
+	defer ts.Close()
+
+	body, err := json.Marshal(&serveRequest{Src: gitSrc, Dst: gitDst})
+	require.NoError(t, err)
+	resp, err := http.Post(ts.URL+"/api/diff", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	d, err := gum.ReadJSONDiff(resp.Body)
+	require.NoError(t, err)
+	assert.NotEmpty(t, d.Matches)
+	require.Len(t, d.Actions, 1)
+	assert.Equal(t, "update", d.Actions[0].Action)
+	assert.Equal(t, "2", d.Actions[0].Label)
+	assert.Equal(t, []*gum.DiffSegment{{Type: "delete", Text: "1"}, {Type: "insert", Text: "2"}}, d.Actions[0].Segments)
+}
+
+func TestServeAPIMatch(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, ""))
+	defer ts.Close()
+
+	resp, err := http.PostForm(ts.URL+"/api/match", url.Values{"src": {gitSrc}, "dst": {gitDst}})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var res map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Contains(t, res, "matches")
+	assert.NotContains(t, res, "actions")
+}
+
+func TestServeAPIDiffDirs(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, "."))
+	defer ts.Close()
+
+	q := url.Values{"srcPath": {"testdata"}, "dstPath": {"testdata"}}
+	resp, err := http.Get(ts.URL + "/api/diff?" + q.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var d jsonDirDiff
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&d))
+	assert.Empty(t, d.Files)
+}
+
+func TestServeWebdiff(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, "."))
+	defer ts.Close()
+
+	q := url.Values{"srcPath": {"testdata/webdiff_src.go"}, "dstPath": {"testdata/webdiff_dst.go"}}
+	resp, err := http.Get(ts.URL + "/diff?" + q.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "data-match=")
+}
+
+func TestServeErrors(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, ""))
+	defer ts.Close()
+
+	resp, err := http.PostForm(ts.URL+"/api/diff", url.Values{"src": {gitSrc}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.PostForm(ts.URL+"/api/diff", url.Values{"src": {"package"}, "dst": {gitDst}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(ts.URL+"/diff", "application/json", strings.NewReader("{"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/unknown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServePaths(t *testing.T) {
+	ts := httptest.NewServer(newServer(parserOptions{Parser: "go"}, "testdata"))
+	defer ts.Close()
+
+	for _, path := range []string{"../serve.go", "webdiff_src.go/../../serve.go", "/etc/passwd"} {
+		q := url.Values{"srcPath": {path}, "dstPath": {"webdiff_dst.go"}}
+		resp, err := http.Get(ts.URL + "/api/diff?" + q.Encode())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, path)
+	}
+
+	q := url.Values{"srcPath": {"./webdiff_src.go"}, "dstPath": {"webdiff_dst.go"}}
+	resp, err := http.Get(ts.URL + "/api/diff?" + q.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// symlinks are resolved before the check
+	root := t.TempDir()
+	src, err := filepath.Abs("testdata/webdiff_src.go")
+	require.NoError(t, err)
+	require.NoError(t, os.Symlink(src, filepath.Join(root, "outside.go")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "inside.go"), []byte(gitSrc), 0644))
+	require.NoError(t, os.Symlink("inside.go", filepath.Join(root, "link.go")))
+	ts3 := httptest.NewServer(newServer(parserOptions{Parser: "go"}, root))
+	defer ts3.Close()
+
+	for path, status := range map[string]int{"outside.go": http.StatusBadRequest, "link.go": http.StatusOK} {
+		q := url.Values{"srcPath": {path}, "dstPath": {"inside.go"}}
+		resp, err := http.Get(ts3.URL + "/api/diff?" + q.Encode())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+
+	// without the root only contents are accepted
+	ts2 := httptest.NewServer(newServer(parserOptions{Parser: "go"}, ""))
+	defer ts2.Close()
+
+	resp, err = http.Get(ts2.URL + "/api/diff?" + q.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.PostForm(ts2.URL+"/api/diff", url.Values{"srcPath": {"../a.go"}, "src": {gitSrc}, "dstPath": {"b.go"}, "dst": {gitDst}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/unified"
+)
+
+// termOptions configure colored output of the diff in a terminal
+type termOptions struct {
+	SideBySide bool `long:"side-by-side" description:"show files in two columns in term mode"`
+	Context    int  `long:"context" default:"3" description:"number of unchanged lines around changes in term and unified modes"`
+	Width      int  `long:"width" default:"160" description:"width of the output in side-by-side term mode"`
+}
+
+// ANSI colors of the groups
+var termColors = map[string]string{
+	"del": "\x1b[31m",
+	"add": "\x1b[32m",
+	"upd": "\x1b[33m",
+	"mv":  "\x1b[36m",
+}
+
+const (
+	termReset  = "\x1b[0m"
+	termHeader = "\x1b[35m"
+)
+
+// writeTermDiff writes the source of both files with highlighted changes
+// collapsing unchanged regions into hunks
+func writeTermDiff(w io.Writer, srcb, dstb []byte, src, dst *gum.Tree, opts termOptions) error {
+	mappings := gum.Match(src, dst)
+	actions := gum.Patch(src, dst, mappings)
+	srcGroups, dstGroups := treeGroups(actions, mappings)
+
+	srcFile := newTermFile(srcb, src, srcGroups, []string{"mv", "upd", "del"})
+	dstFile := newTermFile(dstb, dst, dstGroups, []string{"mv", "upd", "add"})
+
+	// changed words of updated labels
+	dels, inss := labelDiffRanges(actions, mappings, srcb, dstb)
+	for _, r := range dels {
+		srcFile.fill(r[0], r[1], "del")
+	}
+	for _, r := range inss {
+		dstFile.fill(r[0], r[1], "add")
+	}
+
+	// marks of moved subtrees refer to the position on the other side
+	for _, m := range mappings {
+		if !inGroup(srcGroups["mv"], m[0]) {
+			continue
+		}
+		srcFile.mark(m[0], fmt.Sprintf("moved to line %d", unified.LineOf(dstFile.lines, m[1].Pos)+1))
+		dstFile.mark(m[1], fmt.Sprintf("moved from line %d", unified.LineOf(srcFile.lines, m[0].Pos)+1))
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, h := range unified.Hunks(unified.AlignLines(srcFile.toLines(), dstFile.toLines()), opts.Context) {
+		if opts.SideBySide {
+			writeSideBySideHunk(bw, h, srcFile, dstFile, opts.Width)
+		} else {
+			writeInlineHunk(bw, h, srcFile, dstFile)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// termFile is the content of a file with a group of every byte
+type termFile struct {
+	content []byte
+	groups  []string
+	// offsets of the beginnings of the lines
+	lines   []int
+	markers map[int][]string
+}
+
+func newTermFile(content []byte, root *gum.Tree, treeGroups map[string][]*gum.Tree, order []string) *termFile {
+	f := &termFile{
+		content: content,
+		groups:  make([]string, len(content)),
+		lines:   unified.LineStarts(content),
+		markers: make(map[int][]string),
+	}
+
+	// inner nodes go after outer ones in pre-order and override their groups
+	for _, t := range gum.PreOrder(root) {
+		group := ""
+		for _, g := range order {
+			if inGroup(treeGroups[g], t) {
+				group = g
+				break
+			}
+		}
+		if group == "" || t.Length == 0 {
+			continue
+		}
+		f.fill(t.Pos, t.End(), group)
+
+		// only the node itself was added or deleted, not its children
+		if group == "add" || group == "del" {
+			for _, c := range t.Children {
+				if c.Length > 0 {
+					f.fill(c.Pos, c.End(), "")
+				}
+			}
+		}
+	}
+
+	return f
+}
+
+func (f *termFile) fill(start, end int, group string) {
+	if end > len(f.groups) {
+		end = len(f.groups)
+	}
+	for i := start; i < end; i++ {
+		f.groups[i] = group
+	}
+}
+
+func (f *termFile) mark(t *gum.Tree, marker string) {
+	if t.Length == 0 {
+		return
+	}
+	line := unified.LineOf(f.lines, t.Pos)
+	f.markers[line] = append(f.markers[line], marker)
+}
+
+// line returns the range of the line without the new line character
+func (f *termFile) line(i int) (int, int) {
+	start := f.lines[i]
+	end := len(f.content)
+	if i+1 < len(f.lines) {
+		end = f.lines[i+1]
+	}
+	for end > start && (f.content[end-1] == '\n' || f.content[end-1] == '\r') {
+		end--
+	}
+	return start, end
+}
+
+func (f *termFile) isChanged(i int) bool {
+	start, end := f.line(i)
+	for j := start; j < end; j++ {
+		if f.groups[j] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// toLines returns the lines without the new line characters to align
+func (f *termFile) toLines() unified.Lines {
+	l := unified.Lines{Text: make([]string, len(f.lines)), Changed: make([]bool, len(f.lines))}
+	for i := range f.lines {
+		start, end := f.line(i)
+		l.Text[i] = string(f.content[start:end])
+		l.Changed[i] = f.isChanged(i)
+	}
+	return l
+}
+
+func writeInlineHunk(w io.Writer, h *unified.Hunk, src, dst *termFile) {
+	fmt.Fprintln(w, termHeader+h.Header()+termReset)
+	for _, op := range h.Ops {
+		switch op.Op {
+		case unified.Equal:
+			fmt.Fprintln(w, " "+src.render(op.Src, -1))
+		case unified.Delete:
+			fmt.Fprintln(w, "-"+src.render(op.Src, -1)+src.renderMarkers(op.Src))
+		case unified.Insert:
+			fmt.Fprintln(w, "+"+dst.render(op.Dst, -1)+dst.renderMarkers(op.Dst))
+		}
+	}
+}
+
+func writeSideBySideHunk(w io.Writer, h *unified.Hunk, src, dst *termFile, width int) {
+	// line numbers, spaces and the separator take 14 columns
+	colWidth := (width - 14) / 2
+	if colWidth < 10 {
+		colWidth = 10
+	}
+
+	fmt.Fprintln(w, termHeader+h.Header()+termReset)
+	for i := 0; i < len(h.Ops); {
+		if h.Ops[i].Op == unified.Equal {
+			writeSideBySideRow(w, src, h.Ops[i].Src, dst, h.Ops[i].Dst, colWidth)
+			i++
+			continue
+		}
+
+		// pair deleted and inserted lines of the same block
+		var dels, ins []int
+		for ; i < len(h.Ops) && h.Ops[i].Op != unified.Equal; i++ {
+			if h.Ops[i].Op == unified.Delete {
+				dels = append(dels, h.Ops[i].Src)
+			} else {
+				ins = append(ins, h.Ops[i].Dst)
+			}
+		}
+		for j := 0; j < len(dels) || j < len(ins); j++ {
+			s, d := -1, -1
+			if j < len(dels) {
+				s = dels[j]
+			}
+			if j < len(ins) {
+				d = ins[j]
+			}
+			writeSideBySideRow(w, src, s, dst, d, colWidth)
+		}
+	}
+}
+
+func writeSideBySideRow(w io.Writer, src *termFile, s int, dst *termFile, d int, colWidth int) {
+	var markers string
+	cell := func(f *termFile, i int) string {
+		if i < 0 {
+			return strings.Repeat(" ", colWidth+5)
+		}
+		markers += f.renderMarkers(i)
+		return fmt.Sprintf("%4d %s", i+1, f.render(i, colWidth))
+	}
+
+	fmt.Fprintln(w, cell(src, s)+" | "+cell(dst, d)+markers)
+}
+
+// render returns the line with colored groups,
+// if width is not negative tabs are expanded and the line is cut or padded to the width
+func (f *termFile) render(i int, width int) string {
+	start, end := f.line(i)
+
+	var b bytes.Buffer
+	var group string
+	var cols int
+	for j := start; j < end && (width < 0 || cols < width); {
+		r, size := utf8.DecodeRune(f.content[j:end])
+		if g := f.groups[j]; g != group {
+			if group != "" {
+				b.WriteString(termReset)
+			}
+			b.WriteString(termColors[g])
+			group = g
+		}
+
+		if r == '\t' && width >= 0 {
+			n := minInt(4-cols%4, width-cols)
+			b.WriteString(strings.Repeat(" ", n))
+			cols += n
+		} else {
+			b.Write(f.content[j : j+size])
+			cols++
+		}
+		j += size
+	}
+	if group != "" {
+		b.WriteString(termReset)
+	}
+	if cols < width {
+		b.WriteString(strings.Repeat(" ", width-cols))
+	}
+
+	return b.String()
+}
+
+func (f *termFile) renderMarkers(i int) string {
+	var s string
+	for _, m := range f.markers[i] {
+		s += " " + termColors["mv"] + "(" + m + ")" + termReset
+	}
+	return s
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
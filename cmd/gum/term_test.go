@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const termSrc = `package foo
+
+func Foo() {
+	bar(1)
+	println("a")
+	println("b")
+	println("c")
+	println("d")
+	println("e")
+	println("f")
+	println("g")
+}
+
+func Bar() {
+	x := 1
+}
+`
+
+const termDst = `package foo
+
+func Bar() {
+	x := 1
+}
+
+func Foo() {
+	bar(2)
+	println("a")
+	println("b")
+	println("c")
+	println("d")
+	println("e")
+	println("f")
+	println("g")
+	baz()
+}
+`
+
+func termDiff(t *testing.T, opts termOptions) string {
+	src, err := parseContent("src.go", []byte(termSrc), parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("dst.go", []byte(termDst), parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTermDiff(&buf, []byte(termSrc), []byte(termDst), src, dst, opts))
+	return buf.String()
+}
+
+func TestTermDiffInline(t *testing.T) {
+	out := termDiff(t, termOptions{Context: 1})
+
+	assert.Equal(t, "\x1b[35m@@ -2,13 +2,2 @@\x1b[0m\n"+
+		" \n"+
+		"-\x1b[36mfunc Foo() {\x1b[0m \x1b[36m(moved to line 7)\x1b[0m\n"+
+		"-\x1b[36m\tbar(\x1b[0m\x1b[33m1\x1b[0m\x1b[36m)\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"a\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"b\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"c\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"d\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"e\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"f\")\x1b[0m\n"+
+		"-\x1b[36m\tprintln(\"g\")\x1b[0m\n"+
+		"-\x1b[36m}\x1b[0m\n"+
+		"-\n"+
+		" func Bar() {\n"+
+		"\x1b[35m@@ -16,1 +5,13 @@\x1b[0m\n"+
+		" }\n"+
+		"+\n"+
+		"+\x1b[36mfunc Foo() {\x1b[0m \x1b[36m(moved from line 3)\x1b[0m\n"+
+		"+\x1b[36m\tbar(\x1b[0m\x1b[33m2\x1b[0m\x1b[36m)\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"a\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"b\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"c\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"d\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"e\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"f\")\x1b[0m\n"+
+		"+\x1b[36m\tprintln(\"g\")\x1b[0m\n"+
+		"+\x1b[36m\t\x1b[0m\x1b[32mbaz()\x1b[0m\n"+
+		"+\x1b[36m}\x1b[0m\n", out)
+}
+
+func TestTermDiffSideBySide(t *testing.T) {
+	out := termDiff(t, termOptions{Context: 0, SideBySide: true, Width: 40})
+
+	lines := bytes.Split([]byte(out), []byte("\n"))
+	assert.Equal(t, "\x1b[35m@@ -3,11 +2,0 @@\x1b[0m", string(lines[0]))
+	assert.Equal(t, "   3 \x1b[36mfunc Foo() {\x1b[0m  | "+
+		"                   \x1b[36m(moved to line 7)\x1b[0m", string(lines[1]))
+	assert.Equal(t, "   4 \x1b[36m    bar(\x1b[0m\x1b[33m1\x1b[0m\x1b[36m)\x1b[0m"+
+		"    |                   ", string(lines[2]))
+}
+
+func TestTermDiffChangedWord(t *testing.T) {
+	srcb := []byte("package foo\n\nfunc open() {\n\tlog.Println(\"failed to open file\")\n}\n")
+	dstb := []byte("package foo\n\nfunc open() {\n\tlog.Println(\"failed to read file\")\n}\n")
+	src, err := parseContent("src.go", srcb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("dst.go", dstb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTermDiff(&buf, srcb, dstb, src, dst, termOptions{}))
+	assert.Equal(t, "\x1b[35m@@ -4,1 +4,1 @@\x1b[0m\n"+
+		"-\tlog.Println(\x1b[33m\"failed to \x1b[0m\x1b[31mopen\x1b[0m\x1b[33m file\"\x1b[0m)\n"+
+		"+\tlog.Println(\x1b[33m\"failed to \x1b[0m\x1b[32mread\x1b[0m\x1b[33m file\"\x1b[0m)\n", buf.String())
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	src, err := parseContent("src.go", []byte(termSrc), parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("dst.go", []byte(termDst), parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeUnifiedDiff(&buf, "a/foo.go", "b/foo.go", []byte(termSrc), []byte(termDst), src, dst, 1))
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "--- a/foo.go\n+++ b/foo.go\n@@ -2,13 +2,2 @@ moved to line 7\n"), out)
+	assert.Contains(t, out, "@@ -16,1 +5,13 @@ moved from line 3\n")
+	assert.Contains(t, out, "\n+\tbar(2)\n")
+}
@@ -0,0 +1,15 @@
+package foo
+
+import "fmt"
+
+func Print(s string) {
+	fmt.Println("value: " + s)
+}
+
+// Max returns the largest number
+func Max(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
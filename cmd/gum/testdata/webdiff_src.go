@@ -0,0 +1,15 @@
+package foo
+
+import "fmt"
+
+// Max returns the largest number
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Print(s string) {
+	fmt.Println("value:", s)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestWebdiffGolden(t *testing.T) {
+	srcb, err := ioutil.ReadFile("testdata/webdiff_src.go")
+	require.NoError(t, err)
+	dstb, err := ioutil.ReadFile("testdata/webdiff_dst.go")
+	require.NoError(t, err)
+
+	src, err := parseContent("webdiff_src.go", srcb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("webdiff_dst.go", dstb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeWebdiff(&buf, srcb, dstb, src, dst))
+
+	golden := "testdata/webdiff.html"
+	if *updateGolden {
+		require.NoError(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), buf.String())
+}
+
+func TestInteractiveWebdiff(t *testing.T) {
+	srcb, err := ioutil.ReadFile("testdata/webdiff_src.go")
+	require.NoError(t, err)
+	dstb, err := ioutil.ReadFile("testdata/webdiff_dst.go")
+	require.NoError(t, err)
+
+	src, err := parseContent("webdiff_src.go", srcb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("webdiff_dst.go", dstb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeInteractiveWebdiff(&buf, srcb, dstb, src, dst))
+	out := buf.String()
+
+	// moved function is linked with its counterpart
+	fn := src.Children[2]
+	assert.Equal(t, "FuncDecl", fn.Type)
+	assert.Contains(t, out, fmt.Sprintf("<span class='node mv' data-id='s%d' title='FuncDecl' data-match='d%d'>",
+		fn.GetID(), dst.Children[3].GetID()))
+	// updated condition is listed in actions
+	assert.Contains(t, out, "<span class=\"type\">update-attr</span> BinaryExpr op: &gt; → &lt;")
+	// no external resources
+	assert.NotContains(t, out, "<script src")
+	assert.NotContains(t, out, "<link")
+}
+
+func TestWebdiffHTML(t *testing.T) {
+	srcb := []byte(`<p class="note">Hello <b>world</b></p>`)
+	dstb := []byte(`<p class="warning">Hello <b>world</b><br></p>`)
+
+	src, err := parseContent("src.html", srcb, parserOptions{Parser: "html"})
+	require.NoError(t, err)
+	dst, err := parseContent("dst.html", dstb, parserOptions{Parser: "html"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeWebdiff(&buf, srcb, dstb, src, dst))
+	out := buf.String()
+
+	// markup of the documents is escaped and changed nodes are highlighted
+	assert.Contains(t, out, `class=&#34;<span class='upd'>note</span>&#34;`)
+	assert.Contains(t, out, `class=&#34;<span class='upd'>warning</span>&#34;`)
+	assert.Contains(t, out, `<span class='add'>&lt;br&gt;</span>`)
+}
+
+func TestWebdiffChangedWord(t *testing.T) {
+	srcb := []byte("package foo\n\n// open opens the file\nfunc open() {}\n")
+	dstb := []byte("package foo\n\n// open reads the file\nfunc open() {}\n")
+
+	src, err := parseContent("src.go", srcb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+	dst, err := parseContent("dst.go", dstb, parserOptions{Parser: "go"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeWebdiff(&buf, srcb, dstb, src, dst))
+	out := buf.String()
+	assert.Contains(t, out, "<span class='upd'>// open <span class='del'>opens</span> the file</span>")
+	assert.Contains(t, out, "<span class='upd'>// open <span class='add'>reads</span> the file</span>")
+}
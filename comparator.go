@@ -99,6 +99,11 @@ func (c *mappingComparator) numberOfCommonDescendants(src, dst *Tree) int {
 }
 
 func (c *mappingComparator) posInParentSimilarity(src, dst *Tree) float64 {
+	// position in a set doesn't matter
+	if hasUnorderedChildren(src.parent, dst.parent) {
+		return 1
+	}
+
 	posSrc := 0
 	maxSrcPos := 1
 	if !isRoot(src) {
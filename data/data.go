@@ -0,0 +1,161 @@
+// Package data converts documents of data formats (JSON, YAML and TOML) into gum.Tree
+//
+// All formats produce the same shape of the tree:
+// objects and arrays are nodes of types Object and Array,
+// members of objects are nodes with the key as the type and the value as the only child,
+// scalars are leaves with the value as the label.
+//
+// Objects have unordered children,
+// reordering of keys isn't a change of the document and doesn't produce moves.
+package data
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+// Types of the nodes except members of objects which use the key as the type
+const (
+	ObjectType   = "Object"
+	ArrayType    = "Array"
+	StringType   = "String"
+	NumberType   = "Number"
+	BoolType     = "Bool"
+	NullType     = "Null"
+	DatetimeType = "Datetime"
+)
+
+// keyMeta marks members of objects
+type keyMeta struct{}
+
+// IsKey returns true if the node is a member of an object
+func IsKey(t *gum.Tree) bool {
+	_, ok := t.Meta.(keyMeta)
+	return ok
+}
+
+func newKey(name string, pos, end int, value *gum.Tree) *gum.Tree {
+	if value.Length > 0 && value.End() > end {
+		end = value.End()
+	}
+	return &gum.Tree{
+		Type:     name,
+		Meta:     keyMeta{},
+		Pos:      pos,
+		Length:   end - pos,
+		Children: []*gum.Tree{value},
+	}
+}
+
+// finish marks objects as unordered and refreshes the tree
+func finish(t *gum.Tree) *gum.Tree {
+	markObjects(t)
+	t.Refresh()
+	return t
+}
+
+func markObjects(t *gum.Tree) {
+	for _, c := range t.Children {
+		markObjects(c)
+	}
+	t.Unordered = t.Type == ObjectType && !IsKey(t)
+}
+
+// isArray returns true if the node is an array and not a member with the key "Array"
+func isArray(t *gum.Tree) bool {
+	return t.Type == ArrayType && !IsKey(t)
+}
+
+// Path returns location of the node in the document, for example spec.containers[0].image
+//
+// The tree must be refreshed. Values of members have the same path as the members.
+func Path(t *gum.Tree) string {
+	var parts []string
+	for ; t.GetParent() != nil; t = t.GetParent() {
+		p := t.GetParent()
+		switch {
+		case IsKey(t):
+			parts = append(parts, "."+keyString(t.Type))
+		case isArray(p):
+			parts = append(parts, fmt.Sprintf("[%d]", childIndex(p, t)))
+		}
+	}
+
+	var b strings.Builder
+	for i := len(parts) - 1; i >= 0; i-- {
+		b.WriteString(parts[i])
+	}
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+func childIndex(p, t *gum.Tree) int {
+	for i, c := range p.Children {
+		if c == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyString quotes keys that can't be a part of the path as they are
+func keyString(key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\" \t\n") {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+// Describe returns the change made by the action in terms of the document,
+// for example "key spec.replicas updated 2 → 3"
+func Describe(a *gum.Action) string {
+	switch a.Type {
+	case gum.Insert, gum.InsertTree:
+		return subject(a.Node) + " added"
+	case gum.Delete, gum.DeleteTree:
+		return subject(a.Node) + " removed"
+	case gum.Update:
+		name := subject(a.Node)
+		if p := a.Node.GetParent(); p != nil && IsKey(p) {
+			name = subject(p)
+		}
+		return fmt.Sprintf("%s updated %s → %s", name, a.Node.Value, a.Value)
+	case gum.Move:
+		if isArray(a.Parent) {
+			// position is counted before the node is removed from the same parent
+			pos := a.Pos
+			if a.Node.GetParent() == a.Parent && pos > childIndex(a.Parent, a.Node) {
+				pos--
+			}
+			return fmt.Sprintf("%s moved to %s[%d]", subject(a.Node), Path(a.Parent), pos)
+		}
+		return fmt.Sprintf("%s moved into %s", subject(a.Node), location(a.Parent))
+	default:
+		return a.String()
+	}
+}
+
+func subject(t *gum.Tree) string {
+	p := t.GetParent()
+	switch {
+	case IsKey(t):
+		return "key " + Path(t)
+	case p == nil:
+		return "document"
+	case IsKey(p):
+		return "value of key " + Path(t)
+	case isArray(p):
+		return "item " + Path(t)
+	default:
+		return t.Type + " " + Path(t)
+	}
+}
+
+func location(t *gum.Tree) string {
+	if t.GetParent() == nil {
+		return "document"
+	}
+	return Path(t)
+}
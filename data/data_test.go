@@ -0,0 +1,185 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// text returns the source of the node
+func text(src string, t *gum.Tree) string {
+	return src[t.Pos:t.End()]
+}
+
+func describe(t *testing.T, parse func([]byte) (*gum.Tree, error), src, dst string) []string {
+	srcTree, err := parse([]byte(src))
+	require.NoError(t, err)
+	dstTree, err := parse([]byte(dst))
+	require.NoError(t, err)
+
+	var changes []string
+	for _, a := range gum.Patch(srcTree, dstTree, gum.Match(srcTree, dstTree)) {
+		changes = append(changes, Describe(a))
+	}
+	return changes
+}
+
+func TestParseJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `{"name": "web", "ports": [80, 443], "tls": {"enabled": true, "cert": null}}`
+	tree, err := ParseJSON([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(ObjectType, tree.Type)
+	assert.Equal(src, text(src, tree))
+
+	// members keep the order of the source
+	assert.True(tree.Unordered)
+	require.Len(t, tree.Children, 3)
+	name, ports, tls := tree.Children[0], tree.Children[1], tree.Children[2]
+	assert.Equal("name", name.Type)
+	assert.True(IsKey(name))
+	assert.Equal(`"name": "web"`, text(src, name))
+	assert.Equal(StringType, name.Children[0].Type)
+	assert.Equal("web", name.Children[0].Value)
+
+	assert.Equal("[80, 443]", text(src, ports.Children[0]))
+	assert.Equal(NumberType, ports.Children[0].Children[1].Type)
+	assert.Equal("443", ports.Children[0].Children[1].Value)
+	assert.Equal("ports[1]", Path(ports.Children[0].Children[1]))
+
+	cert := tls.Children[0].Children[1]
+	assert.Equal(`"cert": null`, text(src, cert))
+	assert.Equal(NullType, cert.Children[0].Type)
+	assert.Equal("tls.cert", Path(cert))
+
+	_, err = ParseJSON([]byte(`{"a": 1} {}`))
+	assert.Error(err)
+	_, err = ParseJSON([]byte(`{"a": }`))
+	assert.Error(err)
+}
+
+func TestParseYAML(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `name: "web"
+ports:
+  - 80
+  - 443
+script: |
+  echo hello
+  echo bye
+flow: {b: [x, y], a: 1}
+empty:
+`
+	tree, err := ParseYAML([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(ObjectType, tree.Type)
+	var types []string
+	for _, c := range tree.Children {
+		types = append(types, c.Type)
+	}
+	assert.Equal([]string{"name", "ports", "script", "flow", "empty"}, types)
+
+	name, ports, script, flow, empty := tree.Children[0], tree.Children[1], tree.Children[2], tree.Children[3], tree.Children[4]
+	assert.Equal(NullType, empty.Children[0].Type)
+	assert.Equal(`name: "web"`, text(src, name))
+	assert.Equal("web", name.Children[0].Value)
+	assert.Equal("- 80\n  - 443", text(src, ports.Children[0]))
+	assert.Equal(NumberType, ports.Children[0].Children[0].Type)
+	assert.Equal("|\n  echo hello\n  echo bye", text(src, script.Children[0]))
+	assert.Equal("{b: [x, y], a: 1}", text(src, flow.Children[0]))
+	assert.Equal("[x, y]", text(src, flow.Children[0].Children[0].Children[0]))
+	assert.Equal("flow.b[1]", Path(flow.Children[0].Children[0].Children[0].Children[1]))
+}
+
+func TestParseYAMLDocuments(t *testing.T) {
+	assert := assert.New(t)
+
+	src := "a: 1\n---\nb: 2\n"
+	tree, err := ParseYAML([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(ArrayType, tree.Type)
+	require.Len(t, tree.Children, 2)
+	assert.Equal("b: 2", text(src, tree.Children[1]))
+	assert.Equal("[1].b", Path(tree.Children[1].Children[0]))
+
+	_, err = ParseYAML([]byte("a: [1"))
+	assert.Error(err)
+}
+
+func TestDescribeManifest(t *testing.T) {
+	src := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+    tier: frontend
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+          ports:
+            - containerPort: 80
+        - name: sidecar
+          image: envoy:1.28
+          args: ["--log-level", "info"]
+`
+	dst := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    tier: frontend
+    app: web
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: sidecar
+          image: envoy:1.28
+          args: ["--log-level", "info"]
+        - name: web
+          image: nginx:1.25
+          ports:
+            - containerPort: 80
+`
+
+	assert.Equal(t, []string{
+		"key spec.replicas updated 2 → 3",
+		"item spec.template.spec.containers[0] moved to spec.template.spec.containers[1]",
+	}, describe(t, ParseYAML, src, dst))
+}
+
+func TestDescribeJSON(t *testing.T) {
+	src := `{"a": {"x": 1, "y": [1, 2]}, "b": "old"}`
+	dst := `{"b": "old", "a": {"x": 1, "y": [1, 2, 3]}, "c": {"d": true}}`
+
+	assert.Equal(t, []string{
+		"key c added",
+		"item a.y[2] added",
+	}, describe(t, ParseJSON, src, dst))
+
+	assert.Equal(t, []string{
+		"item a.y[2] removed",
+		"key c removed",
+	}, describe(t, ParseJSON, dst, src))
+}
+
+func TestPathQuotesKeys(t *testing.T) {
+	tree, err := ParseJSON([]byte(`{"example.com/name": {"": 1}}`))
+	require.NoError(t, err)
+
+	leaf := tree.Children[0].Children[0].Children[0]
+	assert.Equal(t, `"example.com/name".""`, Path(leaf))
+}
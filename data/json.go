@@ -0,0 +1,115 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/smacker/gum"
+)
+
+// ParseJSON converts JSON document to gum.Tree
+func ParseJSON(content []byte) (*gum.Tree, error) {
+	p := &jsonParser{content: content, dec: json.NewDecoder(bytes.NewReader(content))}
+	p.dec.UseNumber()
+
+	t, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the document at %d", p.dec.InputOffset())
+	}
+
+	return finish(t), nil
+}
+
+type jsonParser struct {
+	content []byte
+	dec     *json.Decoder
+}
+
+// next returns the next token with its position
+func (p *jsonParser) next() (json.Token, int, int, error) {
+	// the decoder skips separators before reading a token
+	start := int(p.dec.InputOffset())
+	for start < len(p.content) && bytes.IndexByte([]byte(" \t\r\n,:"), p.content[start]) >= 0 {
+		start++
+	}
+
+	tok, err := p.dec.Token()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return tok, start, int(p.dec.InputOffset()), nil
+}
+
+func (p *jsonParser) parseValue() (*gum.Tree, error) {
+	tok, start, end, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &gum.Tree{Pos: start, Length: end - start}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			t.Type = ObjectType
+			err = p.parseMembers(t)
+		} else {
+			t.Type = ArrayType
+			err = p.parseItems(t)
+		}
+		if err != nil {
+			return nil, err
+		}
+		// closing delimiter
+		if _, _, end, err = p.next(); err != nil {
+			return nil, err
+		}
+		t.Length = end - start
+	case string:
+		t.Type = StringType
+		t.Value = v
+	case json.Number:
+		t.Type = NumberType
+		t.Value = v.String()
+	case bool:
+		t.Type = BoolType
+		t.Value = fmt.Sprint(v)
+	case nil:
+		t.Type = NullType
+		t.Value = "null"
+	}
+
+	return t, nil
+}
+
+func (p *jsonParser) parseMembers(t *gum.Tree) error {
+	for p.dec.More() {
+		tok, start, end, err := p.next()
+		if err != nil {
+			return err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		t.Children = append(t.Children, newKey(tok.(string), start, end, value))
+	}
+
+	return nil
+}
+
+func (p *jsonParser) parseItems(t *gum.Tree) error {
+	for p.dec.More() {
+		item, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		t.Children = append(t.Children, item)
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/smacker/gum"
+)
+
+// ParseTOML converts TOML document to gum.Tree
+//
+// Tables and arrays of tables become members of the root object,
+// positions of implicitly defined tables cover all their members.
+// Documents are checked by the go-toml decoder, invalid documents are rejected.
+func ParseTOML(content []byte) (*gum.Tree, error) {
+	// the decoder checks values and definitions of tables,
+	// the parser only provides expressions with their positions
+	var v interface{}
+	if err := toml.Unmarshal(content, &v); err != nil {
+		var derr *toml.DecodeError
+		if errors.As(err, &derr) {
+			line, _ := derr.Position()
+			return nil, fmt.Errorf("line %d: %s", line, derr)
+		}
+		return nil, err
+	}
+
+	c := &tomlConverter{content: content}
+	c.parser.Reset(content)
+	root := &gum.Tree{Type: ObjectType, Length: len(content)}
+	table := root
+	for c.parser.NextExpression() {
+		e := c.parser.Expression()
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = c.header(root, e)
+		case unstable.KeyValue:
+			c.keyValue(table, e)
+		}
+	}
+	if err := c.parser.Error(); err != nil {
+		return nil, err
+	}
+
+	spanChildren(root)
+	return finish(root), nil
+}
+
+type tomlConverter struct {
+	content []byte
+	parser  unstable.Parser
+}
+
+type tomlKey struct {
+	name     string
+	pos, end int
+}
+
+func (c *tomlConverter) keys(it unstable.Iterator) []tomlKey {
+	var keys []tomlKey
+	for it.Next() {
+		k := it.Node()
+		pos := int(k.Raw.Offset)
+		keys = append(keys, tomlKey{name: string(k.Data), pos: pos, end: pos + int(k.Raw.Length)})
+	}
+	return keys
+}
+
+// header returns the table of [table] or a new table of [[array of tables]]
+func (c *tomlConverter) header(root *gum.Tree, e *unstable.Node) *gum.Tree {
+	keys := c.keys(e.Key())
+	parent := walk(root, keys[:len(keys)-1])
+	last := keys[len(keys)-1]
+
+	// the header covers the brackets around the key
+	brackets := 1
+	if e.Kind == unstable.ArrayTable {
+		brackets = 2
+	}
+	start, end := keys[0].pos, last.end
+	for c.content[start-1] == ' ' || c.content[start-1] == '\t' {
+		start--
+	}
+	for c.content[end] == ' ' || c.content[end] == '\t' {
+		end++
+	}
+	start, end = start-brackets, end+brackets
+	table := &gum.Tree{Type: ObjectType, Pos: start, Length: end - start}
+
+	member := findMember(parent, last.name)
+	if e.Kind == unstable.Table {
+		if member != nil {
+			// the table was created by a header of its subtable
+			return member.Children[0]
+		}
+		parent.Children = append(parent.Children, newKey(last.name, last.pos, last.end, table))
+		return table
+	}
+
+	if member == nil {
+		member = newKey(last.name, last.pos, last.end, &gum.Tree{Type: ArrayType})
+		parent.Children = append(parent.Children, member)
+	}
+	array := member.Children[0]
+	array.Children = append(array.Children, table)
+	return table
+}
+
+// keyValue adds the member of key = value to the table
+func (c *tomlConverter) keyValue(table *gum.Tree, e *unstable.Node) {
+	keys := c.keys(e.Key())
+	last := keys[len(keys)-1]
+	value := c.value(e.Value(), last.end)
+	parent := walk(table, keys[:len(keys)-1])
+	parent.Children = append(parent.Children, newKey(last.name, last.pos, last.end, value))
+}
+
+// value converts the value node, arrays start at the first bracket after the offset
+func (c *tomlConverter) value(n *unstable.Node, from int) *gum.Tree {
+	switch n.Kind {
+	case unstable.Array:
+		t := &gum.Tree{Type: ArrayType, Pos: c.skip(from)}
+		end := t.Pos + 1
+		for it := n.Children(); it.Next(); {
+			item := c.value(it.Node(), end)
+			t.Children = append(t.Children, item)
+			end = item.End()
+		}
+		t.Length = c.skip(end) + 1 - t.Pos
+		return t
+	case unstable.InlineTable:
+		t := &gum.Tree{Type: ObjectType, Pos: int(n.Raw.Offset)}
+		end := t.Pos + 1
+		for it := n.Children(); it.Next(); {
+			kv := it.Node()
+			c.keyValue(t, kv)
+			end = int(kv.Raw.Offset + kv.Raw.Length)
+		}
+		t.Length = c.skip(end) + 1 - t.Pos
+		return t
+	}
+
+	// values of strings are unescaped, other scalars refer to the content
+	r := n.Raw
+	if r.Length == 0 {
+		r = c.parser.Range(n.Data)
+	}
+	t := &gum.Tree{Pos: int(r.Offset), Length: int(r.Length), Value: string(c.parser.Raw(r))}
+	switch n.Kind {
+	case unstable.String:
+		t.Type, t.Value = StringType, string(n.Data)
+	case unstable.Bool:
+		t.Type = BoolType
+	case unstable.Integer, unstable.Float:
+		t.Type = NumberType
+	default:
+		t.Type = DatetimeType
+	}
+	return t
+}
+
+// skip returns the offset of the next token skipping whitespace, comments and separators
+func (c *tomlConverter) skip(pos int) int {
+	for pos < len(c.content) {
+		switch c.content[pos] {
+		case ' ', '\t', '\r', '\n', ',', '=':
+			pos++
+		case '#':
+			for pos < len(c.content) && c.content[pos] != '\n' {
+				pos++
+			}
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// walk returns the table for the dotted keys creating missing tables,
+// keys pointing to arrays of tables refer to the last table of the array
+func walk(t *gum.Tree, keys []tomlKey) *gum.Tree {
+	for _, k := range keys {
+		member := findMember(t, k.name)
+		if member == nil {
+			member = newKey(k.name, k.pos, k.end, &gum.Tree{Type: ObjectType})
+			t.Children = append(t.Children, member)
+		}
+
+		t = member.Children[0]
+		if t.Type == ArrayType {
+			t = t.Children[len(t.Children)-1]
+		}
+	}
+	return t
+}
+
+func findMember(t *gum.Tree, name string) *gum.Tree {
+	for _, c := range t.Children {
+		if c.Type == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// spanChildren extends positions of the nodes to cover all their children,
+// tables are built from the expressions spread over the document
+func spanChildren(t *gum.Tree) {
+	for _, c := range t.Children {
+		spanChildren(c)
+		if c.Length == 0 {
+			continue
+		}
+		if t.Length == 0 {
+			t.Pos, t.Length = c.Pos, c.Length
+			continue
+		}
+		end := t.End()
+		if c.End() > end {
+			end = c.End()
+		}
+		if c.Pos < t.Pos {
+			t.Pos = c.Pos
+		}
+		t.Length = end - t.Pos
+	}
+}
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTOML(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `title = "config" # comment
+
+[owner]
+name = 'Tom'
+dob = 1979-05-27 07:32:00-08:00
+
+[database.connection]
+ports = [ 8000,
+  8001 ]
+limits = { cpu = 1.5, memory.max = "1Gi" }
+text = """
+multi \
+  line"""
+
+[[servers]]
+name = "alpha"
+
+[[servers]]
+name = "beta"
+`
+	tree, err := ParseTOML([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(ObjectType, tree.Type)
+	var types []string
+	for _, c := range tree.Children {
+		types = append(types, c.Type)
+	}
+	assert.Equal([]string{"title", "owner", "database", "servers"}, types)
+
+	title, owner, database, servers := tree.Children[0], tree.Children[1], tree.Children[2], tree.Children[3]
+
+	assert.Equal(`title = "config"`, text(src, title))
+	assert.Equal("config", title.Children[0].Value)
+
+	assert.Equal("[owner]\nname = 'Tom'\ndob = 1979-05-27 07:32:00-08:00", text(src, owner))
+	dob := owner.Children[0].Children[1]
+	assert.Equal("dob", dob.Type)
+	assert.Equal(DatetimeType, dob.Children[0].Type)
+	assert.Equal("1979-05-27 07:32:00-08:00", dob.Children[0].Value)
+
+	connection := database.Children[0].Children[0].Children[0]
+	assert.Equal("database.connection", Path(connection))
+	ports, limits, textKey := connection.Children[0], connection.Children[1], connection.Children[2]
+	assert.Equal("[ 8000,\n  8001 ]", text(src, ports.Children[0]))
+	assert.Equal("database.connection.ports[1]", Path(ports.Children[0].Children[1]))
+	assert.Equal("{ cpu = 1.5, memory.max = \"1Gi\" }", text(src, limits.Children[0]))
+	memoryMax := limits.Children[0].Children[1].Children[0].Children[0]
+	assert.Equal("database.connection.limits.memory.max", Path(memoryMax))
+	assert.Equal("1Gi", memoryMax.Children[0].Value)
+	assert.Equal("multi line", textKey.Children[0].Value)
+
+	items := servers.Children[0]
+	assert.Equal(ArrayType, items.Type)
+	require.Len(t, items.Children, 2)
+	assert.Equal("[[servers]]\nname = \"beta\"", text(src, items.Children[1]))
+	assert.Equal("beta", items.Children[1].Children[0].Children[0].Value)
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	for _, src := range []string{
+		"a = ",
+		"a = 1\na = 2",
+		"a = 1 b = 2",
+		"[a\n",
+		"a = \"unterminated\n",
+		"a = [1, 2",
+		"a = { b = 1",
+		"a = 1\n[a]",
+		// inline tables and arrays are complete
+		"a = { b = 1 }\na.c = 2",
+		"a = { b = 1 }\n[a]",
+		"a = { b = 1 }\n[a.c]",
+		"a = [{ b = 1 }]\n[[a]]",
+		"a = [{ b = 1 }]\n[a.c]",
+		// tables are defined once
+		"[a]\n[a]",
+		"[a.b]\n[a]\n[a]",
+		"[a]\nb.c = 1\n[a.b]",
+		"[a.b]\nc = 1\n[a]\nb.d = 2",
+		"[[a]]\n[a]",
+		"[a]\n[[a]]",
+		// values are checked
+		"y = 1e",
+		"n = 01",
+		"n = 1__0",
+		"f = .5",
+		"d = 1979-13-45",
+		"t = 25:00:00",
+		`s = "\q"`,
+		"s = \"\"\"unterminated",
+		"b = tru",
+	} {
+		_, err := ParseTOML([]byte(src))
+		assert.Error(t, err, src)
+	}
+
+	for _, src := range []string{
+		"[a.b]\n[a]",
+		"[a]\nb.c = 1\n[a.b.d]",
+		"a.b = 1\na.c = 2",
+		"[[a]]\n[a.b]\n[[a]]\n[a.b]",
+	} {
+		_, err := ParseTOML([]byte(src))
+		assert.NoError(t, err, src)
+	}
+}
+
+func TestParseTOMLValues(t *testing.T) {
+	src := "a = [ [1, 2], # comment\n  [] ]\nb = { c = 0x1F }\nd = 07:32:00\ne = \"\\u00e9\"\n"
+	tree, err := ParseTOML([]byte(src))
+	require.NoError(t, err)
+
+	a, b, d, e := tree.Children[0].Children[0], tree.Children[1].Children[0], tree.Children[2].Children[0], tree.Children[3].Children[0]
+	assert.Equal(t, "[ [1, 2], # comment\n  [] ]", text(src, a))
+	assert.Equal(t, "[1, 2]", text(src, a.Children[0]))
+	assert.Equal(t, "[]", text(src, a.Children[1]))
+	assert.Equal(t, "{ c = 0x1F }", text(src, b))
+	assert.Equal(t, "0x1F", b.Children[0].Children[0].Value)
+	assert.Equal(t, DatetimeType, d.Type)
+	assert.Equal(t, "07:32:00", d.Value)
+	assert.Equal(t, "é", e.Value)
+	assert.Equal(t, `"\u00e9"`, text(src, e))
+}
+
+func TestDescribeTOML(t *testing.T) {
+	src := `[server]
+port = 80
+host = "localhost"
+`
+	dst := `[server]
+host = "localhost"
+port = 8080
+`
+
+	assert.Equal(t, []string{"key server.port updated 80 → 8080"}, describe(t, ParseTOML, src, dst))
+}
+
+func TestDescribeReorder(t *testing.T) {
+	assert.Equal(t, []string{"item a[2] moved to a[0]"}, describe(t, ParseTOML, "a = [1, 2, 3]\n", "a = [3, 1, 2]\n"))
+	assert.Equal(t, []string{"item a[2] moved to a[0]"}, describe(t, ParseJSON, `{"a": [1, 2, 3]}`, `{"a": [3, 1, 2]}`))
+	assert.Equal(t, []string{"item [2] moved to [0]"}, describe(t, ParseJSON, `["x", "y", "z"]`, `["z", "x", "y"]`))
+}
@@ -0,0 +1,260 @@
+package data
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/smacker/gum"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseYAML converts YAML stream to gum.Tree
+//
+// A stream with several documents becomes an array of the documents.
+func ParseYAML(content []byte) (*gum.Tree, error) {
+	p := newYAMLParser(content)
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+
+	var docs []*gum.Tree
+	for {
+		var n yaml.Node
+		err := dec.Decode(&n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, p.toTree(&n, false))
+	}
+
+	switch len(docs) {
+	case 0:
+		return finish(&gum.Tree{Type: NullType, Value: "null"}), nil
+	case 1:
+		return finish(docs[0]), nil
+	}
+
+	t := &gum.Tree{Type: ArrayType, Children: docs}
+	t.Pos = docs[0].Pos
+	t.Length = docs[len(docs)-1].End() - t.Pos
+	return finish(t), nil
+}
+
+type yamlParser struct {
+	content []byte
+	// offsets of the beginnings of the lines
+	lines []int
+}
+
+func newYAMLParser(content []byte) *yamlParser {
+	lines := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &yamlParser{content: content, lines: lines}
+}
+
+// offset converts line and column of the node to the byte offset,
+// columns are counted in characters
+func (p *yamlParser) offset(n *yaml.Node) int {
+	if n.Line < 1 || n.Line > len(p.lines) {
+		return len(p.content)
+	}
+	pos := p.lines[n.Line-1]
+	for i := 1; i < n.Column && pos < len(p.content); i++ {
+		_, size := utf8.DecodeRune(p.content[pos:])
+		pos += size
+	}
+	return pos
+}
+
+func (p *yamlParser) toTree(n *yaml.Node, flow bool) *gum.Tree {
+	start := p.offset(n)
+	flow = flow || n.Style&yaml.FlowStyle != 0
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return &gum.Tree{Type: NullType, Value: "null", Pos: start}
+		}
+		return p.toTree(n.Content[0], false)
+	case yaml.MappingNode:
+		t := &gum.Tree{Type: ObjectType, Pos: start}
+		end := start
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			kStart := p.offset(k)
+			kEnd := p.scalarEnd(k, kStart, flow)
+			value := p.toTree(v, flow)
+			name := k.Value
+			if k.Kind != yaml.ScalarNode {
+				name = string(p.content[kStart:p.nodeEnd(k, kStart, flow)])
+			}
+			member := newKey(name, kStart, kEnd, value)
+			t.Children = append(t.Children, member)
+			if member.End() > end {
+				end = member.End()
+			}
+		}
+		t.Length = p.collectionEnd(n, end, '}') - start
+		return t
+	case yaml.SequenceNode:
+		t := &gum.Tree{Type: ArrayType, Pos: start}
+		end := start
+		for _, c := range n.Content {
+			item := p.toTree(c, flow)
+			t.Children = append(t.Children, item)
+			if item.End() > end {
+				end = item.End()
+			}
+		}
+		t.Length = p.collectionEnd(n, end, ']') - start
+		return t
+	case yaml.AliasNode:
+		return &gum.Tree{Type: StringType, Value: "*" + n.Value, Pos: start, Length: len(n.Value) + 1}
+	default:
+		t := &gum.Tree{Type: scalarType(n), Value: n.Value, Pos: start}
+		if t.Type == NullType {
+			t.Value = "null"
+			// implicit null of a key without value
+			if n.Value == "" {
+				return t
+			}
+		}
+		t.Length = p.scalarEnd(n, start, flow) - start
+		return t
+	}
+}
+
+func scalarType(n *yaml.Node) string {
+	switch n.ShortTag() {
+	case "!!int", "!!float":
+		return NumberType
+	case "!!bool":
+		return BoolType
+	case "!!null":
+		return NullType
+	case "!!timestamp":
+		return DatetimeType
+	default:
+		return StringType
+	}
+}
+
+// nodeEnd returns the end of any node
+func (p *yamlParser) nodeEnd(n *yaml.Node, start int, flow bool) int {
+	if n.Kind == yaml.ScalarNode {
+		return p.scalarEnd(n, start, flow)
+	}
+	t := p.toTree(n, flow)
+	return t.End()
+}
+
+// collectionEnd includes closing bracket of collections in flow style
+func (p *yamlParser) collectionEnd(n *yaml.Node, end int, closing byte) int {
+	if n.Style&yaml.FlowStyle == 0 {
+		return end
+	}
+	if i := bytes.IndexByte(p.content[end:], closing); i >= 0 {
+		return end + i + 1
+	}
+	return end
+}
+
+// scalarEnd finds the end of the scalar in the source,
+// yaml.Node keeps only the start of the node
+func (p *yamlParser) scalarEnd(n *yaml.Node, start int, flow bool) int {
+	c := p.content
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(c); i++ {
+			if c[i] == '\\' {
+				i++
+			} else if c[i] == '"' {
+				return i + 1
+			}
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(c); i++ {
+			if c[i] != '\'' {
+				continue
+			}
+			if i+1 < len(c) && c[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return p.blockScalarEnd(start)
+	default:
+		if bytes.HasPrefix(c[start:], []byte(n.Value)) {
+			return start + len(n.Value)
+		}
+		return p.plainEnd(start, flow)
+	}
+
+	return len(c)
+}
+
+// plainEnd returns the end of a plain scalar that isn't written as is, for example with a tag
+func (p *yamlParser) plainEnd(start int, flow bool) int {
+	end := start
+	for end < len(p.content) && p.content[end] != '\n' {
+		if p.content[end] == '#' && end > start && isYAMLSpace(p.content[end-1]) {
+			break
+		}
+		if flow && strings.IndexByte(",]}", p.content[end]) >= 0 {
+			break
+		}
+		end++
+	}
+	for end > start && isYAMLSpace(p.content[end-1]) {
+		end--
+	}
+	return end
+}
+
+// blockScalarEnd returns the end of the last line of literal or folded scalar
+// that starts with the header at start
+func (p *yamlParser) blockScalarEnd(start int) int {
+	c := p.content
+	end := bytes.IndexByte(c[start:], '\n')
+	if end < 0 {
+		return len(c)
+	}
+	end += start
+
+	indent := -1
+	for pos := end + 1; pos < len(c); {
+		lineEnd := bytes.IndexByte(c[pos:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(c)
+		} else {
+			lineEnd += pos
+		}
+		line := c[pos:lineEnd]
+		if len(bytes.TrimSpace(line)) > 0 {
+			lineIndent := len(line) - len(bytes.TrimLeft(line, " "))
+			if indent < 0 {
+				indent = lineIndent
+			}
+			if lineIndent < indent || indent == 0 {
+				break
+			}
+			end = lineEnd
+		}
+		pos = lineEnd + 1
+	}
+
+	return end
+}
+
+func isYAMLSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
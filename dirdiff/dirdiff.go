@@ -0,0 +1,253 @@
+package dirdiff
+
+import (
+	"sort"
+
+	"github.com/smacker/gum"
+)
+
+const defaultSimThreshold = 0.5
+
+// FileDiff contains differences between a pair of files
+type FileDiff struct {
+	// Empty for added files
+	Src string
+	// Empty for deleted files
+	Dst string
+	// Renamed is true when files were paired by similarity instead of the path
+	Renamed bool
+
+	SrcTree  *gum.Tree
+	DstTree  *gum.Tree
+	Mappings []gum.Mapping
+	Actions  []*gum.Action
+}
+
+// Move describes top-level declaration moved from one file to another
+type Move struct {
+	SrcFile string
+	Src     *gum.Tree
+	DstFile string
+	Dst     *gum.Tree
+}
+
+// Result contains differences between two sets of files
+type Result struct {
+	Files []*FileDiff
+	Moves []*Move
+}
+
+// Differ compares sets of files
+type Differ struct {
+	// Matcher is used to match trees of the files
+	Matcher *gum.Matcher
+	// SimThreshold minimum similarity of two trees to consider one a rename of another
+	// or a declaration moved between files.
+	// Similarity is a ratio of common subtrees to the total number of nodes in both trees.
+	SimThreshold float64
+}
+
+// Compare matches trees of two sets of files keyed by relative paths
+func Compare(src, dst map[string]*gum.Tree) *Result {
+	return NewDiffer().Compare(src, dst)
+}
+
+// NewDiffer creates new Differ with default (recommended) parameters
+func NewDiffer() *Differ {
+	return &Differ{
+		Matcher:      gum.NewMatcher(),
+		SimThreshold: defaultSimThreshold,
+	}
+}
+
+// Compare matches trees of two sets of files keyed by relative paths
+//
+// Files with the same path are paired first,
+// the rest are paired by similarity of their trees.
+// After that top-level declarations deleted in one file and inserted in another
+// are reported as moves.
+func (d *Differ) Compare(src, dst map[string]*gum.Tree) *Result {
+	var srcLeft, dstLeft []string
+	var files []*FileDiff
+	for _, path := range sortedKeys(src) {
+		if _, ok := dst[path]; ok {
+			files = append(files, &FileDiff{Src: path, Dst: path})
+		} else {
+			srcLeft = append(srcLeft, path)
+		}
+	}
+	for _, path := range sortedKeys(dst) {
+		if _, ok := src[path]; !ok {
+			dstLeft = append(dstLeft, path)
+		}
+	}
+
+	renames, srcLeft, dstLeft := d.pairBySimilarity(src, dst, srcLeft, dstLeft)
+	files = append(files, renames...)
+
+	for _, f := range files {
+		f.SrcTree = src[f.Src]
+		f.DstTree = dst[f.Dst]
+		f.Mappings = d.Matcher.Match(f.SrcTree, f.DstTree)
+		f.Actions = gum.Patch(f.SrcTree, f.DstTree, f.Mappings)
+	}
+	for _, path := range srcLeft {
+		files = append(files, &FileDiff{Src: path, SrcTree: src[path]})
+	}
+	for _, path := range dstLeft {
+		files = append(files, &FileDiff{Dst: path, DstTree: dst[path]})
+	}
+
+	return &Result{
+		Files: files,
+		Moves: d.findMoves(files),
+	}
+}
+
+func (d *Differ) pairBySimilarity(src, dst map[string]*gum.Tree, srcPaths, dstPaths []string) ([]*FileDiff, []string, []string) {
+	type candidate struct {
+		src, dst string
+		sim      float64
+	}
+
+	var candidates []candidate
+	for _, s := range srcPaths {
+		for _, t := range dstPaths {
+			sim := d.similarity(src[s], dst[t])
+			if sim >= d.SimThreshold {
+				candidates = append(candidates, candidate{s, t, sim})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].sim > candidates[j].sim })
+
+	var files []*FileDiff
+	pairedSrc := make(map[string]bool)
+	pairedDst := make(map[string]bool)
+	for _, c := range candidates {
+		if pairedSrc[c.src] || pairedDst[c.dst] {
+			continue
+		}
+		pairedSrc[c.src] = true
+		pairedDst[c.dst] = true
+		files = append(files, &FileDiff{Src: c.src, Dst: c.dst, Renamed: true})
+	}
+
+	return files, filterPaths(srcPaths, pairedSrc), filterPaths(dstPaths, pairedDst)
+}
+
+type declaration struct {
+	file string
+	tree *gum.Tree
+}
+
+// declarations deleted from one file and inserted into another
+func (d *Differ) findMoves(files []*FileDiff) []*Move {
+	var deleted, inserted []declaration
+	for _, f := range files {
+		switch {
+		case f.DstTree == nil:
+			for _, c := range f.SrcTree.Children {
+				deleted = append(deleted, declaration{f.Src, c})
+			}
+		case f.SrcTree == nil:
+			for _, c := range f.DstTree.Children {
+				inserted = append(inserted, declaration{f.Dst, c})
+			}
+		default:
+			for _, a := range f.Actions {
+				switch a.Type {
+				case gum.Delete, gum.DeleteTree:
+					if a.Node.GetParent() == f.SrcTree {
+						deleted = append(deleted, declaration{f.Src, a.Node})
+					}
+				case gum.Insert, gum.InsertTree:
+					if a.Node.GetParent() == f.DstTree {
+						inserted = append(inserted, declaration{f.Dst, a.Node})
+					}
+				}
+			}
+		}
+	}
+
+	type candidate struct {
+		del, ins declaration
+		sim      float64
+	}
+
+	var candidates []candidate
+	for _, del := range deleted {
+		// leaves such as package names are too small to be moved
+		if del.tree.GetHeight() < 2 {
+			continue
+		}
+		for _, ins := range inserted {
+			if del.file == ins.file || del.tree.Type != ins.tree.Type {
+				continue
+			}
+			if sim := d.similarity(del.tree, ins.tree); sim >= d.SimThreshold {
+				candidates = append(candidates, candidate{del, ins, sim})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].sim > candidates[j].sim })
+
+	var moves []*Move
+	movedSrc := make(map[*gum.Tree]bool)
+	movedDst := make(map[*gum.Tree]bool)
+	for _, c := range candidates {
+		if movedSrc[c.del.tree] || movedDst[c.ins.tree] {
+			continue
+		}
+		movedSrc[c.del.tree] = true
+		movedDst[c.ins.tree] = true
+		moves = append(moves, &Move{
+			SrcFile: c.del.file,
+			Src:     c.del.tree,
+			DstFile: c.ins.file,
+			Dst:     c.ins.tree,
+		})
+	}
+
+	return moves
+}
+
+// ratio of common subtrees to the total number of nodes in both trees
+//
+// it's a cheap approximation of matching that also works
+// for subtrees that aren't roots of the trees
+func (d *Differ) similarity(src, dst *gum.Tree) float64 {
+	hashes := make(map[[16]byte]int, src.GetSize())
+	for _, t := range gum.PreOrder(src) {
+		hashes[t.GetHash()]++
+	}
+
+	common := 0
+	for _, t := range gum.PreOrder(dst) {
+		if hashes[t.GetHash()] > 0 {
+			hashes[t.GetHash()]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(src.GetSize()+dst.GetSize())
+}
+
+func sortedKeys(m map[string]*gum.Tree) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func filterPaths(paths []string, exclude map[string]bool) []string {
+	var result []string
+	for _, p := range paths {
+		if !exclude[p] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package dirdiff
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, src string) *gum.Tree {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	require.NoError(t, err)
+	return golang.ToTree(f)
+}
+
+func TestCompare(t *testing.T) {
+	assert := assert.New(t)
+
+	src := map[string]*gum.Tree{
+		"a.go": parse(t, `package foo
+
+func Foo(a int) int {
+	return a + 1
+}
+
+func Bar(b string) string {
+	if b == "" {
+		return "empty"
+	}
+	return b
+}
+`),
+		"b.go": parse(t, `package foo
+
+func Baz() {}
+`),
+		"old.go": parse(t, `package foo
+
+type Qux struct {
+	Name string
+	Age  int
+}
+
+func (q *Qux) String() string {
+	return q.Name
+}
+`),
+	}
+	dst := map[string]*gum.Tree{
+		"a.go": parse(t, `package foo
+
+func Foo(a int) int {
+	return a + 2
+}
+`),
+		"b.go": parse(t, `package foo
+
+func Baz() {}
+
+func Bar(b string) string {
+	if b == "" {
+		return "empty"
+	}
+	return b
+}
+`),
+		"new.go": parse(t, `package foo
+
+type Qux struct {
+	Name string
+	Age  int
+}
+
+func (q *Qux) String() string {
+	return q.Name + "!"
+}
+`),
+	}
+
+	res := Compare(src, dst)
+
+	require.Len(t, res.Files, 3)
+	f := res.Files[0]
+	assert.Equal("a.go", f.Src)
+	assert.Equal("a.go", f.Dst)
+	assert.False(f.Renamed)
+	assert.NotEmpty(f.Actions)
+
+	f = res.Files[1]
+	assert.Equal("b.go", f.Src)
+	assert.Equal("b.go", f.Dst)
+
+	f = res.Files[2]
+	assert.Equal("old.go", f.Src)
+	assert.Equal("new.go", f.Dst)
+	assert.True(f.Renamed)
+	assert.NotEmpty(f.Actions)
+
+	require.Len(t, res.Moves, 1)
+	m := res.Moves[0]
+	assert.Equal("a.go", m.SrcFile)
+	assert.Equal("b.go", m.DstFile)
+	assert.Equal("FuncDecl", m.Src.Type)
+	assert.True(m.Src.IsIsomorphicTo(m.Dst))
+}
+
+func TestCompareAddedDeleted(t *testing.T) {
+	assert := assert.New(t)
+
+	src := map[string]*gum.Tree{
+		"a.go": parse(t, `package foo
+
+func Foo(a int) int {
+	return a + 1
+}
+`),
+	}
+	dst := map[string]*gum.Tree{
+		"b.go": parse(t, `package bar
+
+var x = []string{"a", "b", "c"}
+`),
+	}
+
+	res := Compare(src, dst)
+
+	require.Len(t, res.Files, 2)
+	assert.Equal("a.go", res.Files[0].Src)
+	assert.Equal("", res.Files[0].Dst)
+	assert.Nil(res.Files[0].Actions)
+	assert.Equal("", res.Files[1].Src)
+	assert.Equal("b.go", res.Files[1].Dst)
+	assert.Len(res.Moves, 0)
+}
@@ -1,8 +1,17 @@
 module github.com/smacker/gum
 
-go 1.13
+go 1.21.0
 
 require (
+	github.com/pelletier/go-toml/v2 v2.3.1
 	github.com/sergi/go-diff v1.0.0
 	github.com/stretchr/testify v1.4.0
+	golang.org/x/net v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
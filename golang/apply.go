@@ -0,0 +1,429 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+
+	"github.com/smacker/gum"
+)
+
+// ApplyToSource applies the actions to the source and returns the formatted result
+//
+// The actions must be generated for a tree of the same source built by ToTree.
+// Inserted nodes are taken from Meta of the dst nodes of the actions.
+// Nodes come from different files, so the result is printed with a new layout.
+// It's equal to dst semantically but not byte by byte: comments not attached to nodes
+// are dropped and every statement, declaration and field starts a new line.
+func ApplyToSource(src []byte, actions []*gum.Action) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	srcTree := ToTree(f)
+
+	res, err := gum.Apply(srcTree, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	// original parents are used to find the fields of the nodes
+	parents := make(map[ast.Node]parent)
+	addParents := func(t *gum.Tree, inDst bool) {
+		for _, n := range gum.PreOrder(t) {
+			for _, c := range n.Children {
+				parents[c.Meta.(ast.Node)] = parent{n.Meta.(ast.Node), inDst}
+			}
+		}
+	}
+	addParents(srcTree, false)
+	for _, a := range actions {
+		if a.Type != gum.Insert && a.Type != gum.InsertTree {
+			continue
+		}
+		if _, ok := a.Node.Meta.(ast.Node); !ok {
+			return nil, fmt.Errorf("inserted node %s doesn't have ast.Node in Meta", a.Node)
+		}
+		if p := a.Node.GetParent(); p != nil {
+			parents[a.Node.Meta.(ast.Node)] = parent{p.Meta.(ast.Node), true}
+		}
+		addParents(a.Node, true)
+	}
+
+	b := &builder{parents: parents}
+	node, err := b.build(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, layout(node.(*ast.File)), node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parent is the original parent of a node
+type parent struct {
+	node ast.Node
+	// inserted nodes keep their parents from dst,
+	// while moved nodes of src may have different fields in the new parents
+	inDst bool
+}
+
+// role is the name of the field of a node in its original parent
+type role struct {
+	name  string
+	inDst bool
+}
+
+// builder converts gum.Tree back to go/ast
+type builder struct {
+	// original parents of the nodes in src and dst files
+	parents map[ast.Node]parent
+}
+
+// build returns copies of ast nodes in Meta with the children of the tree
+// and without positions
+func (b *builder) build(t *gum.Tree) (ast.Node, error) {
+	orig, ok := t.Meta.(ast.Node)
+	if !ok {
+		return nil, fmt.Errorf("node %s doesn't have ast.Node in Meta", t)
+	}
+	v := reflect.New(reflect.TypeOf(orig).Elem())
+	v.Elem().Set(reflect.ValueOf(orig).Elem())
+	node := v.Interface().(ast.Node)
+
+	fields := nodeFields(node)
+	filled := make([]bool, len(fields))
+	for i, f := range fields {
+		filled[i] = !f.value.IsZero()
+		f.value.Set(reflect.Zero(f.value.Type()))
+	}
+	if err := setLabel(node, t); err != nil {
+		return nil, err
+	}
+
+	children := make([]ast.Node, len(t.Children))
+	roles := make([]role, len(t.Children))
+	for i, c := range t.Children {
+		child, err := b.build(c)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = child
+		roles[i] = b.role(orig, c.Meta.(ast.Node))
+	}
+
+	assigned, ok := assignFields(node, fields, filled, children, roles)
+	if !ok {
+		return nil, fmt.Errorf("children of %s don't fit fields of %T", t, node)
+	}
+	for i, child := range children {
+		f := fields[assigned[i]]
+		if f.value.Kind() == reflect.Slice {
+			f.value.Set(reflect.Append(f.value, reflect.ValueOf(child)))
+		} else {
+			f.value.Set(reflect.ValueOf(child))
+		}
+	}
+
+	// go/printer panics on missing required fields
+	for _, f := range fields {
+		if requiredFields[typeName(node)+"."+f.name] && f.value.IsZero() {
+			return nil, fmt.Errorf("%s of %s is missing", f.name, t)
+		}
+	}
+
+	switch n := node.(type) {
+	case *ast.SliceExpr:
+		n.Slice3 = n.Max != nil
+	case *ast.CallExpr:
+		// "..." isn't a node, it's kept from the original call
+		if len(n.Args) == 0 {
+			n.Ellipsis = token.NoPos
+		}
+	}
+
+	return node, nil
+}
+
+// role returns the field of the child in its original parent
+// if the parent has the same type as the new one
+func (b *builder) role(parent, child ast.Node) role {
+	orig, ok := b.parents[child]
+	if !ok || reflect.TypeOf(orig.node) != reflect.TypeOf(parent) {
+		return role{}
+	}
+	for _, f := range nodeFields(orig.node) {
+		if f.value.Kind() != reflect.Slice {
+			if !f.value.IsNil() && f.value.Interface() == child {
+				return role{f.name, orig.inDst}
+			}
+			continue
+		}
+		for i := 0; i < f.value.Len(); i++ {
+			if f.value.Index(i).Interface() == child {
+				return role{f.name, orig.inDst}
+			}
+		}
+	}
+	return role{}
+}
+
+// field of ast node that holds children
+type field struct {
+	name  string
+	value reflect.Value
+}
+
+// nodeFields returns fields visited by toTree in their order
+func nodeFields(node ast.Node) []field {
+	v := reflect.ValueOf(node).Elem()
+	nodeType := reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+	var fields []field
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		// not visited by toTree
+		if _, ok := node.(*ast.File); ok && sf.Name != "Doc" && sf.Name != "Name" && sf.Name != "Decls" {
+			continue
+		}
+
+		t := sf.Type
+		if t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && t.Implements(nodeType) {
+			fields = append(fields, field{name: sf.Name, value: v.Field(i)})
+		}
+	}
+
+	return fields
+}
+
+// assignFields finds a field for every child keeping the order of the fields
+//
+// Optional fields make the order ambiguous, for example, the only statement of ForStmt
+// besides the body can be Init or Post, so the assignment that keeps the original roles
+// and the original set of filled fields wins.
+func assignFields(node ast.Node, fields []field, filled []bool, children []ast.Node, roles []role) ([]int, bool) {
+	typeName := typeName(node)
+	n, m := len(children), len(fields)
+	isSlice := func(j int) bool {
+		return fields[j].value.Kind() == reflect.Slice
+	}
+	fits := func(i, j, used int) bool {
+		t := fields[j].value.Type()
+		if isSlice(j) {
+			t = t.Elem()
+		} else if used == 1 {
+			return false
+		}
+		return reflect.TypeOf(children[i]).AssignableTo(t)
+	}
+	// roles of moved nodes may be outdated, while missing required fields break the syntax
+	const srcRoleCost, filledCost, dstRoleCost, requiredCost = 1, 2, 4, 1 << 16
+	assignCost := func(i, j, used int) int {
+		c := 0
+		if r := roles[i]; r.name != "" && r.name != fields[j].name {
+			if r.inDst {
+				c += dstRoleCost
+			} else {
+				c += srcRoleCost
+			}
+		}
+		if used == 0 && !filled[j] {
+			c += filledCost
+		}
+		return c
+	}
+	skipCost := func(j, used int) int {
+		switch {
+		case used == 1:
+			return 0
+		case requiredFields[typeName+"."+fields[j].name]:
+			return requiredCost
+		case filled[j]:
+			return filledCost
+		}
+		return 0
+	}
+
+	// best[i][j][used] is the minimal cost of assigning children from i to fields from j,
+	// used means the field j has a child already
+	const inf = 1 << 30
+	best := make([][][2]int, n+1)
+	for i := range best {
+		best[i] = make([][2]int, m+1)
+		if i < n {
+			best[i][m] = [2]int{inf, inf}
+		}
+	}
+	for i := n; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			for used := 0; used < 2; used++ {
+				res := skipCost(j, used) + best[i][j+1][0]
+				if i < n && fits(i, j, used) {
+					if c := assignCost(i, j, used) + best[i+1][j][1]; c <= res {
+						res = c
+					}
+				}
+				best[i][j][used] = res
+			}
+		}
+	}
+	if best[0][0][0] >= inf {
+		return nil, false
+	}
+
+	assigned := make([]int, n)
+	for i, j, used := 0, 0, 0; i < n; {
+		if fits(i, j, used) && assignCost(i, j, used)+best[i+1][j][1] == best[i][j][used] {
+			assigned[i] = j
+			i++
+			used = 1
+			continue
+		}
+		j++
+		used = 0
+	}
+
+	return assigned, true
+}
+
+// requiredFields are never empty in parsed files
+var requiredFields = map[string]bool{
+	"Field.Type":            true,
+	"FuncLit.Type":          true,
+	"FuncLit.Body":          true,
+	"ParenExpr.X":           true,
+	"SelectorExpr.X":        true,
+	"SelectorExpr.Sel":      true,
+	"IndexExpr.X":           true,
+	"IndexExpr.Index":       true,
+	"IndexListExpr.X":       true,
+	"IndexListExpr.Indices": true,
+	"SliceExpr.X":           true,
+	"TypeAssertExpr.X":      true,
+	"CallExpr.Fun":          true,
+	"StarExpr.X":            true,
+	"UnaryExpr.X":           true,
+	"BinaryExpr.X":          true,
+	"BinaryExpr.Y":          true,
+	"KeyValueExpr.Key":      true,
+	"KeyValueExpr.Value":    true,
+	"ArrayType.Elt":         true,
+	"StructType.Fields":     true,
+	"FuncType.Params":       true,
+	"InterfaceType.Methods": true,
+	"MapType.Key":           true,
+	"MapType.Value":         true,
+	"ChanType.Value":        true,
+	"DeclStmt.Decl":         true,
+	"LabeledStmt.Label":     true,
+	"LabeledStmt.Stmt":      true,
+	"ExprStmt.X":            true,
+	"SendStmt.Chan":         true,
+	"SendStmt.Value":        true,
+	"IncDecStmt.X":          true,
+	"AssignStmt.Lhs":        true,
+	"AssignStmt.Rhs":        true,
+	"GoStmt.Call":           true,
+	"DeferStmt.Call":        true,
+	"IfStmt.Cond":           true,
+	"IfStmt.Body":           true,
+	"SwitchStmt.Body":       true,
+	"TypeSwitchStmt.Assign": true,
+	"TypeSwitchStmt.Body":   true,
+	"SelectStmt.Body":       true,
+	"ForStmt.Body":          true,
+	"RangeStmt.X":           true,
+	"RangeStmt.Body":        true,
+	"ImportSpec.Path":       true,
+	"ValueSpec.Names":       true,
+	"TypeSpec.Name":         true,
+	"TypeSpec.Type":         true,
+	"FuncDecl.Name":         true,
+	"FuncDecl.Type":         true,
+	"File.Name":             true,
+}
+
+var operators = func() map[string]token.Token {
+	ops := make(map[string]token.Token)
+	for i := 0; i < 256; i++ {
+		if tok := token.Token(i); tok.IsOperator() {
+			ops[tok.String()] = tok
+		}
+	}
+	return ops
+}()
+
+// setLabel sets the properties of the node stored in the label and attributes of the tree
+func setLabel(node ast.Node, t *gum.Tree) error {
+	lookup := func(s string, tokens map[string]token.Token) (token.Token, error) {
+		if tok, ok := tokens[s]; ok {
+			return tok, nil
+		}
+		if tok := token.Lookup(s); tok.IsKeyword() {
+			return tok, nil
+		}
+		return token.ILLEGAL, fmt.Errorf("unknown token %q of %s", s, t)
+	}
+	attr := func(key string) (token.Token, error) {
+		s, _ := t.GetAttr(key)
+		return lookup(s, operators)
+	}
+
+	var err error
+	switch n := node.(type) {
+	case *ast.Comment:
+		n.Text = t.Value
+	case *ast.Ident:
+		n.Name = t.Value
+	case *ast.BasicLit:
+		n.Value = t.Value
+	case *ast.ChanType:
+		switch t.Value {
+		case "chan<-":
+			n.Dir = ast.SEND
+		case "<-chan":
+			n.Dir = ast.RECV
+		default:
+			n.Dir = ast.SEND | ast.RECV
+		}
+	case *ast.UnaryExpr:
+		n.Op, err = attr("op")
+	case *ast.BinaryExpr:
+		n.Op, err = attr("op")
+	case *ast.IncDecStmt:
+		n.Tok, err = attr("tok")
+	case *ast.AssignStmt:
+		n.Tok, err = attr("tok")
+	case *ast.RangeStmt:
+		n.Tok = token.ILLEGAL
+		if _, ok := t.GetAttr("tok"); ok {
+			n.Tok, err = attr("tok")
+		}
+	case *ast.BranchStmt:
+		n.Tok, err = lookup(t.Value, nil)
+	case *ast.GenDecl:
+		n.Tok, err = lookup(t.Value, nil)
+	case *ast.TypeSpec:
+		// any valid position is replaced by the layout
+		n.Assign = token.NoPos
+		if t.Value == "=" {
+			n.Assign = token.Pos(1)
+		}
+	}
+
+	return err
+}
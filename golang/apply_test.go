@@ -0,0 +1,152 @@
+package golang
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTree(t *testing.T, src []byte) *gum.Tree {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	require.NoError(t, err)
+	return ToTree(f)
+}
+
+// roundTrip applies the actions between src and dst to a copy of src
+// and checks the result has the same tree as dst
+func roundTrip(t *testing.T, src, dst []byte) []byte {
+	srcTree, dstTree := parseTree(t, src), parseTree(t, dst)
+	actions := gum.Patch(srcTree, dstTree, gum.Match(srcTree, dstTree))
+
+	res, err := ApplyToSource(src, actions)
+	require.NoError(t, err)
+	resTree := parseTree(t, res)
+	require.True(t, resTree.IsIsomorphicTo(dstTree), "result:\n%s", res)
+
+	return res
+}
+
+func TestApplyToSource(t *testing.T) {
+	cases := []struct {
+		name     string
+		src, dst string
+	}{{
+		name: "update",
+		src:  "package foo\n\nfunc f(a int) int {\n\treturn a + 1\n}\n",
+		dst:  "package foo\n\nfunc g(a int) int {\n\treturn a * 2\n}\n",
+	}, {
+		name: "insert and delete",
+		src:  "package foo\n\nimport \"fmt\"\n\nfunc f() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n}\n",
+		dst:  "package foo\n\nimport \"fmt\"\n\nfunc f() {\n\tx := 2\n\tfmt.Println(x)\n}\n\nfunc g() {}\n",
+	}, {
+		name: "move",
+		src:  "package foo\n\nfunc f(a, b int) {\n\tif a > b {\n\t\ta, b = b, a\n\t}\n\tprintln(a - b)\n}\n",
+		dst:  "package foo\n\nfunc f(a, b int) {\n\tprintln(b - a)\n\tif a > b {\n\t\ta, b = b, a\n\t\tprintln(a)\n\t}\n}\n",
+	}, {
+		name: "optional fields",
+		src:  "package foo\n\nfunc f(s []int) {\n\tfor i := 0; i < 1; i++ {\n\t}\n\t_ = s[1:]\n}\n",
+		dst:  "package foo\n\nfunc f(s []int) {\n\tfor ; ; i++ {\n\t}\n\t_ = s[:1]\n\t_ = s[1:2:3]\n}\n",
+	}, {
+		name: "comments",
+		src:  "package foo\n\n// T is a type\ntype T struct {\n\tA int // a\n}\n",
+		dst:  "package foo\n\n// T is the type\ntype T struct {\n\t// B is b\n\tB int\n\tA int // a\n}\n\ntype U = T\n",
+	}, {
+		name: "tokens",
+		src:  "package foo\n\nvar x = 1\n\nfunc f(c chan int, xs ...int) {\n\tx += -1\n\tfor range xs {\n\t\tbreak\n\t}\n\tg(xs...)\n}\n",
+		dst:  "package foo\n\nconst x = 1\n\nfunc f(c <-chan int, xs ...int) {\n\tx -= ^1\n\tfor i := range xs {\n\t\tcontinue\n\t}\n\tg(xs...)\n}\n",
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			roundTrip(t, []byte(c.src), []byte(c.dst))
+		})
+	}
+}
+
+func TestApplyToSourceFiles(t *testing.T) {
+	files := []string{"golang.go", "types.go", "apply.go", "golang_test.go"}
+	for _, src := range files {
+		for _, dst := range files {
+			srcb, err := os.ReadFile(src)
+			require.NoError(t, err)
+			dstb, err := os.ReadFile(dst)
+			require.NoError(t, err)
+
+			t.Run(src+" to "+dst, func(t *testing.T) {
+				roundTrip(t, srcb, dstb)
+			})
+		}
+	}
+}
+
+func TestApplyToSourceErrors(t *testing.T) {
+	src := []byte("package foo\n\nfunc f() {}\n")
+	dst := []byte("package foo\n\nfunc g() {\n\tf()\n}\n")
+	srcTree, dstTree := parseTree(t, src), parseTree(t, dst)
+	actions := gum.Patch(srcTree, dstTree, gum.Match(srcTree, dstTree))
+
+	// the source doesn't parse
+	_, err := ApplyToSource([]byte("package"), actions)
+	assert.Error(t, err)
+
+	// the actions are generated for another source
+	_, err = ApplyToSource(dst, actions)
+	assert.Error(t, err)
+}
+
+func TestApplyToSourcePort(t *testing.T) {
+	base := []byte(`package foo
+
+func handle(req string) error {
+	if req == "" {
+		return errors.New(fmt.Sprintf("empty %s", req))
+	}
+	return nil
+}
+`)
+	upstream := []byte(`package foo
+
+func handle(req string) error {
+	if req == "" {
+		return fmt.Errorf("empty %s", req)
+	}
+	return nil
+}
+`)
+	fork := []byte(`package foo
+
+// handle is patched in the fork
+func handle(req string) error {
+	log(req)
+	if req == "" {
+		return errors.New(fmt.Sprintf("empty %s", req))
+	}
+	return process(req)
+}
+`)
+
+	baseTree, upstreamTree := parseTree(t, base), parseTree(t, upstream)
+	actions := gum.Patch(baseTree, upstreamTree, gum.Match(baseTree, upstreamTree))
+	ported, failed, err := gum.Port(actions, baseTree, parseTree(t, fork))
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+
+	res, err := ApplyToSource(fork, ported)
+	require.NoError(t, err)
+	assert.Equal(t, `package foo
+
+// handle is patched in the fork
+func handle(req string) error {
+	log(req)
+	if req == "" {
+		return fmt.Errorf("empty %s", req)
+	}
+	return process(req)
+}
+`, string(res))
+}
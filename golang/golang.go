@@ -1,22 +1,66 @@
 package golang
 
 import (
-	"fmt"
 	"go/ast"
+	"go/token"
 	"reflect"
 
 	"github.com/smacker/gum"
 )
 
 // ToTree converts ast.File to gum.Tree
+//
+// Positions of the nodes are filled only if the file was parsed by go/parser
 func ToTree(f *ast.File) *gum.Tree {
 	t := toTree(f)
+	if f.FileStart.IsValid() {
+		setPositions(t, f.FileStart)
+		t.Pos = 0
+		t.Length = int(f.FileEnd - f.FileStart)
+	}
 	t.Refresh()
 	return t
 }
 
+// setPositions fills byte offsets of the nodes relative to the base
+//
+// range of a node covers all its children because
+// some nodes, for example FuncDecl, don't include the doc comment
+func setPositions(t *gum.Tree, base token.Pos) {
+	n := t.Meta.(ast.Node)
+	start, end := n.Pos(), n.End()
+	for _, c := range t.Children {
+		setPositions(c, base)
+
+		// FuncType of FuncDecl starts with "func" keyword
+		// and overlaps receiver and name of the function
+		if _, ok := n.(*ast.FuncDecl); ok {
+			if _, ok := c.Meta.(*ast.FuncType); ok && len(c.Children) > 0 {
+				c.Length = c.End() - c.Children[0].Pos
+				c.Pos = c.Children[0].Pos
+			}
+		}
+
+		if c.Length == 0 {
+			continue
+		}
+		if cStart := base + token.Pos(c.Pos); !start.IsValid() || cStart < start {
+			start = cStart
+		}
+		if cEnd := base + token.Pos(c.End()); cEnd > end {
+			end = cEnd
+		}
+	}
+
+	if start.IsValid() {
+		t.Pos = int(start - base)
+		t.Length = int(end - start)
+	}
+}
+
 func toTree(node ast.Node) *gum.Tree {
 	var token string
+	var attrs []gum.Attr
 	var children []*gum.Tree
 
 	switch n := node.(type) {
@@ -73,6 +117,11 @@ func toTree(node ast.Node) *gum.Tree {
 	case *ast.IndexExpr:
 		children = append(children, toTree(n.X))
 		children = append(children, toTree(n.Index))
+	case *ast.IndexListExpr:
+		children = append(children, toTree(n.X))
+		for _, x := range n.Indices {
+			children = append(children, toTree(x))
+		}
 	case *ast.SliceExpr:
 		children = append(children, toTree(n.X))
 		if n.Low != nil {
@@ -97,10 +146,10 @@ func toTree(node ast.Node) *gum.Tree {
 	case *ast.StarExpr:
 		children = append(children, toTree(n.X))
 	case *ast.UnaryExpr:
-		token = n.Op.String()
+		attrs = append(attrs, gum.Attr{Key: "op", Value: n.Op.String()})
 		children = append(children, toTree(n.X))
 	case *ast.BinaryExpr:
-		token = n.Op.String()
+		attrs = append(attrs, gum.Attr{Key: "op", Value: n.Op.String()})
 		children = append(children, toTree(n.X))
 		children = append(children, toTree(n.Y))
 	case *ast.KeyValueExpr:
@@ -115,6 +164,9 @@ func toTree(node ast.Node) *gum.Tree {
 	case *ast.StructType:
 		children = append(children, toTree(n.Fields))
 	case *ast.FuncType:
+		if n.TypeParams != nil {
+			children = append(children, toTree(n.TypeParams))
+		}
 		if n.Params != nil {
 			children = append(children, toTree(n.Params))
 		}
@@ -127,6 +179,14 @@ func toTree(node ast.Node) *gum.Tree {
 		children = append(children, toTree(n.Key))
 		children = append(children, toTree(n.Value))
 	case *ast.ChanType:
+		switch n.Dir {
+		case ast.SEND:
+			token = "chan<-"
+		case ast.RECV:
+			token = "<-chan"
+		default:
+			token = "chan"
+		}
 		children = append(children, toTree(n.Value))
 	// Statements
 	case *ast.BadStmt:
@@ -144,10 +204,10 @@ func toTree(node ast.Node) *gum.Tree {
 		children = append(children, toTree(n.Chan))
 		children = append(children, toTree(n.Value))
 	case *ast.IncDecStmt:
-		token = n.Tok.String()
+		attrs = append(attrs, gum.Attr{Key: "tok", Value: n.Tok.String()})
 		children = append(children, toTree(n.X))
 	case *ast.AssignStmt:
-		token = n.Tok.String()
+		attrs = append(attrs, gum.Attr{Key: "tok", Value: n.Tok.String()})
 		for _, x := range n.Lhs {
 			children = append(children, toTree(x))
 		}
@@ -222,8 +282,8 @@ func toTree(node ast.Node) *gum.Tree {
 		}
 		children = append(children, toTree(n.Body))
 	case *ast.RangeStmt:
-		token = n.Tok.String()
 		if n.Key != nil {
+			attrs = append(attrs, gum.Attr{Key: "tok", Value: n.Tok.String()})
 			children = append(children, toTree(n.Key))
 		}
 		if n.Value != nil {
@@ -264,6 +324,13 @@ func toTree(node ast.Node) *gum.Tree {
 			children = append(children, toTree(n.Doc))
 		}
 		children = append(children, toTree(n.Name))
+		if n.TypeParams != nil {
+			children = append(children, toTree(n.TypeParams))
+		}
+		// alias declaration
+		if n.Assign.IsValid() {
+			token = "="
+		}
 		children = append(children, toTree(n.Type))
 		if n.Comment != nil {
 			children = append(children, toTree(n.Comment))
@@ -307,15 +374,64 @@ func toTree(node ast.Node) *gum.Tree {
 			children = append(children, toTree(f))
 		}
 	default:
-		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+		// nodes added to go/ast later than this function
+		children = genericChildren(node)
 	}
 
 	tree := &gum.Tree{
-		Type:     reflect.TypeOf(node).Elem().Name(),
+		Type:     typeName(node),
 		Value:    token,
+		Attrs:    attrs,
 		Children: children,
 		Meta:     node,
 	}
+	// order of imports doesn't matter
+	if _, ok := node.(*ast.GenDecl); ok && token == "import" {
+		tree.Unordered = true
+	}
 
 	return tree
 }
+
+func typeName(node ast.Node) string {
+	t := reflect.TypeOf(node)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// genericChildren finds children of unknown nodes in their fields
+func genericChildren(node ast.Node) []*gum.Tree {
+	v := reflect.Indirect(reflect.ValueOf(node))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var children []*gum.Tree
+	add := func(f reflect.Value) {
+		if (f.Kind() == reflect.Ptr || f.Kind() == reflect.Interface) && f.IsNil() {
+			return
+		}
+		if n, ok := f.Interface().(ast.Node); ok {
+			children = append(children, toTree(n))
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			add(f)
+		case reflect.Slice:
+			for j := 0; j < f.Len(); j++ {
+				add(f.Index(j))
+			}
+		}
+	}
+
+	return children
+}
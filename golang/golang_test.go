@@ -2,11 +2,18 @@ package golang
 
 import (
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/smacker/gum"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestToTree(t *testing.T) {
@@ -28,3 +35,195 @@ func bar() {
 	assert.NoError(err)
 	fmt.Println(ToTree(f))
 }
+
+func TestToTreePositions(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `package foo
+
+// bar does nothing
+func (f *foo) bar() {
+	return
+}
+`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	assert.NoError(err)
+	tree := ToTree(f)
+
+	text := func(t *gum.Tree) string {
+		return src[t.Pos:t.End()]
+	}
+
+	assert.Equal(src, text(tree))
+	fn := tree.Children[1]
+	assert.Equal("FuncDecl", fn.Type)
+	// doc comment is a part of the declaration
+	assert.Equal(src[13:len(src)-1], text(fn))
+	assert.Equal("(f *foo)", text(fn.Children[1]))
+	assert.Equal("bar", text(fn.Children[2]))
+	// type of the function doesn't overlap the receiver and the name
+	assert.Equal("()", text(fn.Children[3]))
+	assert.Equal("return", text(fn.Children[4].Children[0]))
+}
+
+func TestToTreeGenerics(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `package foo
+
+type Number interface {
+	~int | ~float64
+}
+
+type Pair[K comparable, V Number] struct {
+	Key   K
+	Value V
+}
+
+type Alias = Pair[string, int]
+
+func Sum[T Number](xs ...T) (s T) {
+	var p Pair[string, T]
+	_ = p
+	return s
+}
+`
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	assert.NoError(err)
+	tree := ToTree(f)
+
+	types := make(map[string]bool)
+	for _, n := range gum.PreOrder(tree) {
+		types[n.Type] = true
+	}
+	assert.True(types["IndexListExpr"])
+
+	pair := tree.Children[2].Children[0]
+	assert.Equal("TypeSpec", pair.Type)
+	assert.Equal("FieldList", pair.Children[1].Type)
+	assert.Equal("[K comparable, V Number]", src[pair.Children[1].Pos:pair.Children[1].End()])
+
+	alias := tree.Children[3].Children[0]
+	assert.Equal("=", alias.Value)
+
+	sum := tree.Children[4]
+	fnType := sum.Children[1]
+	assert.Equal("FuncType", fnType.Type)
+	assert.Equal("[T Number](xs ...T) (s T)", src[fnType.Pos:fnType.End()])
+}
+
+// unknownNode is a node type that ToTree doesn't know
+type unknownNode struct {
+	X    ast.Expr
+	List []ast.Stmt
+	skip ast.Expr
+}
+
+func (n *unknownNode) Pos() token.Pos { return n.X.Pos() }
+func (n *unknownNode) End() token.Pos { return n.X.End() }
+
+func TestToTreeUnknownNode(t *testing.T) {
+	n := &unknownNode{
+		X:    ast.NewIdent("x"),
+		List: []ast.Stmt{&ast.EmptyStmt{}},
+		skip: ast.NewIdent("y"),
+	}
+	tree := toTree(n)
+	assert.Equal(t, "unknownNode", tree.Type)
+	require.Len(t, tree.Children, 2)
+	assert.Equal(t, "x", tree.Children[0].Value)
+	assert.Equal(t, "EmptyStmt", tree.Children[1].Type)
+}
+
+// walks all sources of the standard library to make sure any syntax is supported
+func TestToTreeGoRoot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	root := filepath.Join(runtime.GOROOT(), "src")
+	var files int
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			// testdata contains broken files on purpose
+			return nil
+		}
+
+		// ToTree without Refresh, hashing of huge generated files is slow
+		tree := toTree(f)
+		setPositions(tree, f.FileStart)
+		require.Equal(t, "File", tree.Type, path)
+		files++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, files)
+}
+
+// converts a few sources of the standard library with the exported ToTree
+func TestToTreeGoRootSample(t *testing.T) {
+	for _, name := range []string{"fmt/print.go", "go/ast/ast.go", "sort/sort.go", "strings/builder.go"} {
+		path := filepath.Join(runtime.GOROOT(), "src", filepath.FromSlash(name))
+		f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+		require.NoError(t, err)
+
+		tree := ToTree(f)
+		require.Equal(t, "File", tree.Type, name)
+		assert.Equal(t, tree.GetSize()-1, tree.GetID(), name)
+		assert.Equal(t, int(f.FileEnd-f.FileStart), tree.End(), name)
+	}
+}
+
+func TestToTreeImportsUnordered(t *testing.T) {
+	parse := func(src string) *gum.Tree {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		require.NoError(t, err)
+		return ToTree(f)
+	}
+
+	src := parse("package foo\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strings\"\n)\n")
+	dst := parse("package foo\n\nimport (\n\t\"strings\"\n\t\"fmt\"\n\t\"os\"\n)\n")
+
+	assert.True(t, src.Children[1].Unordered)
+	assert.Empty(t, gum.Patch(src, dst, gum.Match(src, dst)))
+}
+
+func TestToTreeOperatorAttrs(t *testing.T) {
+	parse := func(src string) *gum.Tree {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+		require.NoError(t, err)
+		return ToTree(f)
+	}
+
+	src := parse("package foo\n\nfunc f(a, b int) {\n\ta += b * 2\n\tfor i := range a {\n\t}\n}\n")
+	dst := parse("package foo\n\nfunc f(a, b int) {\n\ta -= b * 2\n\tfor i = range a {\n\t}\n}\n")
+
+	body := src.Children[1].Children[2]
+	assign, loop := body.Children[0], body.Children[1]
+	assert.Equal(t, []gum.Attr{{Key: "tok", Value: "+="}}, assign.Attrs)
+	assert.Empty(t, assign.Value)
+	op, _ := assign.Children[1].GetAttr("op")
+	assert.Equal(t, "*", op)
+	tok, _ := loop.GetAttr("tok")
+	assert.Equal(t, ":=", tok)
+
+	actions := gum.Patch(src, dst, gum.Match(src, dst))
+	require.Len(t, actions, 2)
+	for i, want := range []string{"-=", "="} {
+		assert.Equal(t, gum.UpdateAttr, actions[i].Type)
+		assert.Equal(t, "tok", actions[i].Key)
+		assert.Equal(t, want, actions[i].Value)
+	}
+	assert.Equal(t, assign, actions[0].Node)
+	assert.Equal(t, loop, actions[1].Node)
+}
@@ -0,0 +1,199 @@
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+)
+
+// layout sets positions of the file built from nodes of different files
+// and returns the file set they belong to
+//
+// Statements, declarations, specs of grouped declarations, fields of structs and interfaces
+// and comments of doc groups start new lines, other nodes continue the line.
+// Positions before the children of a node are set to its start and closing ones to its end,
+// so go/printer keeps comments next to their nodes.
+func layout(f *ast.File) *token.FileSet {
+	l := &layouter{lines: []int{0}, blockLists: make(map[*ast.FieldList]bool)}
+	l.node(f, nil)
+	f.Comments = l.comments
+
+	fset := token.NewFileSet()
+	tf := fset.AddFile("", fset.Base(), l.off+1)
+	tf.SetLines(l.lines)
+	l.setBase(token.Pos(tf.Base()))
+
+	return fset
+}
+
+// gap between positions of the nodes, longer than any token
+const layoutGap = 16
+
+type layouter struct {
+	off   int
+	lines []int
+	// positions are set relative to the beginning of the file after the walk
+	positions []reflect.Value
+	comments  []*ast.CommentGroup
+	// fields of structs and interfaces, unlike parameters, go on separate lines
+	blockLists map[*ast.FieldList]bool
+}
+
+func (l *layouter) newline() {
+	l.off++
+	l.lines = append(l.lines, l.off)
+}
+
+// set sets valid positions, invalid ones mean missing tokens, for example "..." of CallExpr
+func (l *layouter) set(v reflect.Value, off int) {
+	if !v.Interface().(token.Pos).IsValid() {
+		return
+	}
+	v.Set(reflect.ValueOf(token.Pos(off)))
+	l.positions = append(l.positions, v)
+}
+
+func (l *layouter) setBase(base token.Pos) {
+	for _, v := range l.positions {
+		v.Set(reflect.ValueOf(base + v.Interface().(token.Pos)))
+	}
+}
+
+func (l *layouter) node(n, parent ast.Node) {
+	if g, ok := n.(*ast.CommentGroup); ok {
+		l.commentGroup(g)
+		return
+	}
+
+	switch n := n.(type) {
+	case *ast.StructType:
+		l.blockLists[n.Fields] = true
+	case *ast.InterfaceType:
+		l.blockLists[n.Methods] = true
+	}
+	startsLine := l.startsLine(n, parent)
+	if startsLine {
+		l.newline()
+		// blank line between top-level declarations
+		if _, ok := parent.(*ast.File); ok {
+			l.newline()
+		}
+	}
+
+	// doc comment goes on the lines before the node
+	fields := nodeFields(n)
+	for _, f := range fields {
+		if f.name == "Doc" && !f.value.IsNil() {
+			if !startsLine {
+				l.newline()
+			}
+			l.node(f.value.Interface().(ast.Node), n)
+			l.newline()
+		}
+	}
+
+	v := reflect.ValueOf(n).Elem()
+	var closing []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Type() != posType {
+			continue
+		}
+		if isClosing(v.Type().Field(i).Name) {
+			closing = append(closing, v.Field(i))
+			continue
+		}
+		l.set(v.Field(i), l.off)
+	}
+	start := l.off
+	l.off += layoutGap
+	switch n := n.(type) {
+	case *ast.Ident:
+		l.off += len(n.Name)
+	case *ast.BasicLit:
+		l.off += len(n.Value)
+	}
+
+	for _, f := range fields {
+		if f.name == "Doc" {
+			continue
+		}
+		if f.value.Kind() != reflect.Slice {
+			if !f.value.IsNil() {
+				l.node(f.value.Interface().(ast.Node), n)
+			}
+			continue
+		}
+		for i := 0; i < f.value.Len(); i++ {
+			l.node(f.value.Index(i).Interface().(ast.Node), n)
+		}
+	}
+
+	if l.closesLine(n) {
+		l.newline()
+	}
+	for _, c := range closing {
+		l.set(c, l.off)
+	}
+	l.off += layoutGap
+
+	// "func" keyword goes before the receiver and the name
+	if n, ok := n.(*ast.FuncDecl); ok && n.Type.Func.IsValid() {
+		n.Type.Func = token.Pos(start)
+	}
+}
+
+// commentGroup puts comments on separate lines starting from the current one
+func (l *layouter) commentGroup(g *ast.CommentGroup) {
+	l.comments = append(l.comments, g)
+	for i, c := range g.List {
+		if i > 0 {
+			l.newline()
+		}
+		l.set(reflect.ValueOf(&c.Slash).Elem(), l.off)
+		l.off += len(c.Text) + layoutGap
+	}
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+func isClosing(name string) bool {
+	switch name {
+	case "Rbrace", "Rparen", "Rbrack", "Closing", "EndPos":
+		return true
+	}
+	return false
+}
+
+// startsLine returns true if the node goes on a new line in its parent
+func (l *layouter) startsLine(n, parent ast.Node) bool {
+	switch p := parent.(type) {
+	case *ast.File:
+		_, ok := n.(ast.Decl)
+		return ok
+	case *ast.GenDecl:
+		return p.Lparen.IsValid()
+	case *ast.BlockStmt:
+		return true
+	case *ast.CaseClause:
+		_, ok := n.(ast.Stmt)
+		return ok
+	case *ast.CommClause:
+		return n != p.Comm
+	case *ast.FieldList:
+		return l.blockLists[p]
+	}
+	return false
+}
+
+// closesLine returns true if the closing token of the node goes on a new line
+func (l *layouter) closesLine(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		return len(n.List) > 0
+	case *ast.GenDecl:
+		return n.Lparen.IsValid()
+	case *ast.FieldList:
+		return len(n.List) > 0 && l.blockLists[n]
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package golang
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+
+	"github.com/smacker/gum"
+)
+
+// ToTypedTree converts ast.File to gum.Tree
+// and sets Ref of identifiers to the objects they resolve to in info
+func ToTypedTree(f *ast.File, info *types.Info) *gum.Tree {
+	t := ToTree(f)
+	for _, n := range gum.PreOrder(t) {
+		ident, ok := n.Meta.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		if obj := info.ObjectOf(ident); obj != nil {
+			n.Ref = objectRef(obj)
+		}
+	}
+
+	return t
+}
+
+// objectRef returns identifier of the object unique in the file set
+func objectRef(obj types.Object) string {
+	if obj.Pos().IsValid() {
+		return fmt.Sprintf("%s@%d", obj.Name(), obj.Pos())
+	}
+	// objects of the universe scope
+	return types.ObjectString(obj, nil)
+}
+
+// TypeCheck type-checks files of one package without network or build cache,
+// imports are loaded from the sources.
+//
+// Errors are ignored to resolve as much as possible in incomplete packages.
+func TypeCheck(fset *token.FileSet, files []*ast.File) *types.Info {
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	if len(files) > 0 {
+		_, _ = conf.Check(files[0].Name.Name, fset, files, info)
+	}
+
+	return info
+}
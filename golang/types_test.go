@@ -0,0 +1,111 @@
+package golang
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTyped(t *testing.T, src string) *gum.Tree {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	require.NoError(t, err)
+	return ToTypedTree(f, TypeCheck(fset, []*ast.File{f}))
+}
+
+func TestToTypedTree(t *testing.T) {
+	tree := parseTyped(t, `package foo
+
+var x = 1
+
+func f(x int) int {
+	return x + len("")
+}
+
+func g() int {
+	return x
+}
+`)
+
+	refs := make(map[string][]string)
+	for _, n := range gum.PreOrder(tree) {
+		if n.Type == "Ident" && n.Value == "x" {
+			require.NotEmpty(t, n.Ref)
+			refs[n.Ref] = append(refs[n.Ref], n.Value)
+		}
+	}
+	// global and parameter are different objects
+	assert.Len(t, refs, 2)
+	for _, uses := range refs {
+		assert.Len(t, uses, 2)
+	}
+
+	for _, n := range gum.PreOrder(tree) {
+		if n.Value == "len" {
+			assert.Equal(t, "builtin len", n.Ref)
+		}
+	}
+}
+
+func TestTypedRename(t *testing.T) {
+	src := parseTyped(t, `package foo
+
+type T struct{}
+
+func (T) get() int { return 1 }
+
+func use(t T) int {
+	a := t.get()
+	b := t.get() + t.get()
+	return a + b
+}
+`)
+	dst := parseTyped(t, `package foo
+
+type T struct{}
+
+func (T) value() int { return 1 }
+
+func use(t T) int {
+	a := t.value()
+	b := t.value() + t.value()
+	return a + b
+}
+`)
+
+	actions := gum.Patch(src, dst, gum.Match(src, dst))
+	renames, rest := gum.Renames(actions)
+	assert.Empty(t, rest)
+	require.Len(t, renames, 1)
+	assert.Equal(t, "get", renames[0].From)
+	assert.Equal(t, "value", renames[0].To)
+	assert.Len(t, renames[0].Nodes, 4)
+}
+
+func TestTypedMatchPrefersSameObject(t *testing.T) {
+	src := parseTyped(t, `package foo
+
+func f(p, q int) int {
+	return h(p, q)
+}
+`)
+	// both parameters are renamed and arguments are swapped,
+	// without types the arguments are mapped by position
+	dst := parseTyped(t, `package foo
+
+func f(p2, q2 int) int {
+	return h(q2, p2)
+}
+`)
+
+	for _, m := range gum.Match(src, dst) {
+		if m[0].Type == "Ident" && m[0].GetParent().Type == "CallExpr" && m[0].Value != "h" {
+			assert.Equal(t, m[0].Value+"2", m[1].Value, "%s mapped to %s", m[0], m[1])
+		}
+	}
+}
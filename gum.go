@@ -28,6 +28,10 @@ const (
 	Update
 	// Move a single node
 	Move
+	// UpdateAttr changes a single attribute of a node
+	UpdateAttr
+	// DeleteAttr removes a single attribute of a node
+	DeleteAttr
 )
 
 func (o Operation) String() string {
@@ -44,6 +48,10 @@ func (o Operation) String() string {
 		return "update"
 	case Move:
 		return "move"
+	case UpdateAttr:
+		return "update-attr"
+	case DeleteAttr:
+		return "delete-attr"
 	default:
 		return "unknown operation"
 	}
@@ -53,12 +61,14 @@ func (o Operation) String() string {
 type Action struct {
 	Type Operation
 	Node *Tree
-	// Empty for Delete, DeleteTree and Update
+	// Empty for Delete, DeleteTree, Update, UpdateAttr and DeleteAttr
 	Parent *Tree
 	// Empty for any Type expect Insert, InsertTree and Move
 	Pos int
-	// Empty for any Type except Update
+	// Empty for any Type except Update and UpdateAttr
 	Value string
+	// Key of the attribute, empty for any Type except UpdateAttr and DeleteAttr
+	Key string
 }
 
 func (a *Action) String() string {
@@ -75,6 +85,10 @@ func (a *Action) String() string {
 		return fmt.Sprintf("update: %s; value: %s", a.Node, a.Value)
 	case Move:
 		return fmt.Sprintf("move: %s; parent: %s; pos: %d", a.Node, a.Parent, a.Pos)
+	case UpdateAttr:
+		return fmt.Sprintf("update-attr: %s; key: %s; value: %s", a.Node, a.Key, a.Value)
+	case DeleteAttr:
+		return fmt.Sprintf("delete-attr: %s; key: %s", a.Node, a.Key)
 	default:
 		return "unknown operation"
 	}
@@ -122,5 +136,8 @@ func (m *Matcher) Match(src, dst *Tree) []Mapping {
 	bum := newBottomUpMatcher(mappings)
 	bum.maxSize = m.MaxSize
 	bum.simThreshold = m.SimThreshold
-	return bum.Match(src, dst).ToList()
+	mappings = bum.Match(src, dst)
+
+	matchRefs(mappings)
+	return mappings.ToList()
 }
@@ -166,3 +166,167 @@ func deepCompare(t *testing.T, a *Tree, b *Tree) {
 		require.Equal(t, len(b.Children), len(a.Children))
 	}
 }
+
+// newSet returns a tree with a set of pairs under the root,
+// pairs are the key and the value
+func newSet(unordered bool, pairs ...string) *Tree {
+	set := &Tree{Type: "Set", Unordered: unordered}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		set.Children = append(set.Children, &Tree{Type: "Pair", Value: pairs[i], Children: []*Tree{
+			{Type: "Name", Value: pairs[i]},
+			{Type: "Value", Value: pairs[i+1]},
+		}})
+	}
+	root := &Tree{Type: "Root", Children: []*Tree{set}}
+	root.Refresh()
+	return root
+}
+
+func TestUnorderedChildren(t *testing.T) {
+	cases := []struct {
+		name     string
+		src, dst []string
+		updates  []string
+	}{
+		{"reordered", []string{"a", "1", "b", "2", "c", "3"}, []string{"c", "3", "a", "1", "b", "2"}, nil},
+		{"one changed", []string{"a", "1", "b", "2", "c", "3"}, []string{"c", "3", "b", "20", "a", "1"}, []string{"20"}},
+		{"all changed", []string{"a", "1", "b", "2"}, []string{"b", "20", "a", "10"}, []string{"20", "10"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src, dst := newSet(true, c.src...), newSet(true, c.dst...)
+			mappings := Match(src, dst)
+			actions := Patch(src, dst, mappings)
+
+			var updates []string
+			for _, a := range actions {
+				require.Equal(t, Update, a.Type, a.String())
+				updates = append(updates, a.Value)
+			}
+			assert.ElementsMatch(t, c.updates, updates)
+
+			// pairs are mapped by the key
+			for _, m := range mappings {
+				if m[0].Type == "Pair" {
+					assert.Equal(t, m[0].Value, m[1].Value)
+				}
+			}
+
+			// ordered children of the same trees are moved or mapped by position
+			src, dst = newSet(false, c.src...), newSet(false, c.dst...)
+			assert.Greater(t, len(Patch(src, dst, Match(src, dst))), len(actions))
+		})
+	}
+}
+
+func TestUnorderedIsomorphic(t *testing.T) {
+	src, dst := newSet(true, "a", "1", "b", "2"), newSet(true, "b", "2", "a", "1")
+	assert.True(t, src.IsIsomorphicTo(dst))
+
+	src, dst = newSet(false, "a", "1", "b", "2"), newSet(false, "b", "2", "a", "1")
+	assert.False(t, src.IsIsomorphicTo(dst))
+}
+
+func TestMarkUnordered(t *testing.T) {
+	tree := newSet(false, "a", "1")
+	MarkUnordered(tree, "Set")
+	assert.False(t, tree.Unordered)
+	assert.True(t, tree.Children[0].Unordered)
+	assert.False(t, tree.Children[0].Children[0].Unordered)
+}
+
+func TestAttrs(t *testing.T) {
+	assert := assert.New(t)
+
+	tree := &Tree{Type: "AssignStmt"}
+	tree.SetAttr("tok", "+=")
+	tree.SetAttr("define", "false")
+	tree.SetAttr("tok", "-=")
+	assert.Equal([]Attr{{"tok", "-="}, {"define", "false"}}, tree.Attrs)
+
+	v, ok := tree.GetAttr("tok")
+	assert.True(ok)
+	assert.Equal("-=", v)
+	_, ok = tree.GetAttr("op")
+	assert.False(ok)
+
+	other := &Tree{Type: "AssignStmt", Attrs: []Attr{{"tok", "+="}, {"define", "false"}}}
+	tree.Refresh()
+	other.Refresh()
+	assert.False(tree.EqualAttrs(other))
+	assert.False(tree.IsIsomorphicTo(other))
+
+	other.SetAttr("tok", "-=")
+	other.Refresh()
+	assert.True(tree.EqualAttrs(other))
+	assert.True(tree.IsIsomorphicTo(other))
+}
+
+func TestUpdateAttr(t *testing.T) {
+	assert := assert.New(t)
+
+	newAssign := func(attrs ...Attr) *Tree {
+		tree := &Tree{Type: "Block", Children: []*Tree{
+			{Type: "AssignStmt", Attrs: attrs, Children: []*Tree{
+				{Type: "Ident", Value: "x"},
+				{Type: "BasicLit", Value: "1"},
+			}},
+			{Type: "Ident", Value: "y"},
+		}}
+		tree.Refresh()
+		return tree
+	}
+
+	src := newAssign(Attr{"tok", "+="}, Attr{"label", "a"}, Attr{"empty", "x"})
+	dst := newAssign(Attr{"tok", "-="}, Attr{"empty", ""})
+	actions := Patch(src, dst, Match(src, dst))
+	require.Len(t, actions, 3)
+
+	assert.Equal(UpdateAttr, actions[0].Type)
+	assert.Equal(src.Children[0], actions[0].Node)
+	assert.Equal("tok", actions[0].Key)
+	assert.Equal("-=", actions[0].Value)
+	assert.Equal("update-attr: AssignStmt@@; key: tok; value: -=", actions[0].String())
+
+	// attributes with empty values aren't removed
+	assert.Equal(UpdateAttr, actions[1].Type)
+	assert.Equal("empty", actions[1].Key)
+	assert.Equal("", actions[1].Value)
+
+	assert.Equal(DeleteAttr, actions[2].Type)
+	assert.Equal("label", actions[2].Key)
+	assert.Equal("delete-attr: AssignStmt@@; key: label", actions[2].String())
+
+	assert.Equal(treeString(dst), treeString(apply(src, actions)))
+	_, hasLabel := src.Children[0].GetAttr("label")
+	assert.True(hasLabel, "Patch must not change the src tree")
+}
+
+func TestUnorderedChildrenSimilarity(t *testing.T) {
+	// items have the same type and label, only their content tells them apart
+	newItems := func(pairs ...string) *Tree {
+		set := newSet(true, pairs...)
+		for _, p := range set.Children[0].Children {
+			p.Value = ""
+		}
+		set.Refresh()
+		return set
+	}
+	src := newItems("a", "1", "b", "2")
+	dst := newItems("b", "20", "a", "10")
+
+	mappings := Match(src, dst)
+	for _, m := range mappings {
+		if m[0].Type == "Pair" {
+			assert.Equal(t, m[0].Children[0].Value, m[1].Children[0].Value)
+		}
+	}
+
+	var updates []string
+	for _, a := range Patch(src, dst, mappings) {
+		require.Equal(t, Update, a.Type, a.String())
+		updates = append(updates, a.Value)
+	}
+	assert.ElementsMatch(t, []string{"20", "10"}, updates)
+}
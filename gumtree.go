@@ -0,0 +1,621 @@
+package gum
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file implements formats of the reference java implementation (GumTree 2.1)
+// for trees and diffs. Nodes are referenced by ids assigned in post-order by Refresh
+// which is the same convention as in the reference implementation.
+
+// ReadTreeJSON reads a tree in GumTree JSON format: {"root": {"typeLabel": ..., "children": [...]}}
+//
+// Numeric "type" is ignored if "typeLabel" is present.
+func ReadTreeJSON(r io.Reader) (*Tree, error) {
+	var root struct {
+		T *jsonTree `json:"root"`
+	}
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, err
+	}
+	if root.T == nil {
+		return nil, fmt.Errorf("root node not found")
+	}
+
+	t := root.T.toTree()
+	t.Refresh()
+
+	return t, nil
+}
+
+// WriteTreeJSON writes the tree in GumTree JSON format
+func WriteTreeJSON(w io.Writer, t *Tree) error {
+	b, err := json.MarshalIndent(struct {
+		T *jsonTree `json:"root"`
+	}{newJSONTree(t)}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type jsonTree struct {
+	Type      string      `json:"type,omitempty"`
+	Label     string      `json:"label,omitempty"`
+	TypeLabel string      `json:"typeLabel"`
+	Pos       *stringInt  `json:"pos,omitempty"`
+	Length    *stringInt  `json:"length,omitempty"`
+	Attrs     []jsonAttr  `json:"attrs,omitempty"`
+	Children  []*jsonTree `json:"children"`
+}
+
+// attributes of the nodes are an extension of the format
+type jsonAttr struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func newJSONTree(t *Tree) *jsonTree {
+	jt := &jsonTree{
+		Label:     t.Value,
+		TypeLabel: t.Type,
+		Children:  make([]*jsonTree, len(t.Children)),
+	}
+	if t.Length > 0 {
+		pos, length := stringInt(t.Pos), stringInt(t.Length)
+		jt.Pos, jt.Length = &pos, &length
+	}
+	for _, a := range t.Attrs {
+		jt.Attrs = append(jt.Attrs, jsonAttr(a))
+	}
+	for i, c := range t.Children {
+		jt.Children[i] = newJSONTree(c)
+	}
+
+	return jt
+}
+
+func (jt *jsonTree) toTree() *Tree {
+	t := &Tree{
+		Type:     jt.TypeLabel,
+		Value:    jt.Label,
+		Children: make([]*Tree, len(jt.Children)),
+	}
+	if t.Type == "" {
+		t.Type = jt.Type
+	}
+	if jt.Pos != nil && jt.Length != nil {
+		t.Pos, t.Length = int(*jt.Pos), int(*jt.Length)
+	}
+	for _, a := range jt.Attrs {
+		t.Attrs = append(t.Attrs, Attr(a))
+	}
+	for i, c := range jt.Children {
+		t.Children[i] = c.toTree()
+	}
+
+	return t
+}
+
+// stringInt is an integer encoded as a string in json, numbers are accepted too
+type stringInt int
+
+func (i stringInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.Itoa(int(i)))
+}
+
+func (i *stringInt) UnmarshalJSON(b []byte) error {
+	v, err := strconv.Atoi(strings.Trim(string(b), `"`))
+	if err != nil {
+		return fmt.Errorf("incorrect integer %s: %s", b, err)
+	}
+	*i = stringInt(v)
+	return nil
+}
+
+// ReadTreeXML reads a tree in GumTree XML format: <root><tree typeLabel="..."><tree/></tree></root>
+//
+// Attributes of the nodes are read from <attr key="..." value="..."/> elements inside of the tree.
+func ReadTreeXML(r io.Reader) (*Tree, error) {
+	d := xml.NewDecoder(r)
+
+	var stack []*Tree
+	var root *Tree
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local == "attr" && len(stack) > 0 {
+				t := stack[len(stack)-1]
+				t.Attrs = append(t.Attrs, xmlToAttr(tok))
+				continue
+			}
+			if tok.Name.Local != "tree" {
+				continue
+			}
+			t, err := xmlToTree(tok)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, t)
+			} else if root == nil {
+				root = t
+			}
+			stack = append(stack, t)
+		case xml.EndElement:
+			if tok.Name.Local == "tree" {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("root node not found")
+	}
+	root.Refresh()
+
+	return root, nil
+}
+
+func xmlToTree(el xml.StartElement) (*Tree, error) {
+	t := &Tree{}
+	var typ string
+	var pos, length int
+	var hasPos bool
+	var err error
+	for _, attr := range el.Attr {
+		switch attr.Name.Local {
+		case "type":
+			typ = attr.Value
+		case "typeLabel":
+			t.Type = attr.Value
+		case "label":
+			t.Value = attr.Value
+		case "pos":
+			pos, err = strconv.Atoi(attr.Value)
+			hasPos = true
+		case "length":
+			length, err = strconv.Atoi(attr.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("incorrect attribute %s: %s", attr.Name.Local, err)
+		}
+	}
+	if t.Type == "" {
+		t.Type = typ
+	}
+	if hasPos {
+		t.Pos, t.Length = pos, length
+	}
+
+	return t, nil
+}
+
+func xmlToAttr(el xml.StartElement) Attr {
+	var a Attr
+	for _, attr := range el.Attr {
+		switch attr.Name.Local {
+		case "key":
+			a.Key = attr.Value
+		case "value":
+			a.Value = attr.Value
+		}
+	}
+	return a
+}
+
+// WriteTreeXML writes the tree in GumTree XML format
+func WriteTreeXML(w io.Writer, t *Tree) error {
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	root := xml.StartElement{Name: xml.Name{Local: "root"}}
+	if err := e.EncodeToken(root); err != nil {
+		return err
+	}
+	ctx := xml.StartElement{Name: xml.Name{Local: "context"}}
+	if err := e.EncodeToken(ctx); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(ctx.End()); err != nil {
+		return err
+	}
+	if err := writeXMLTree(e, t); err != nil {
+		return err
+	}
+	if err := e.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := e.Flush(); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeXMLTree(e *xml.Encoder, t *Tree) error {
+	el := xml.StartElement{Name: xml.Name{Local: "tree"}}
+	if t.Value != "" {
+		el.Attr = append(el.Attr, xmlAttr("label", t.Value))
+	}
+	el.Attr = append(el.Attr, xmlAttr("typeLabel", t.Type))
+	if t.Length > 0 {
+		el.Attr = append(el.Attr, xmlAttr("pos", strconv.Itoa(t.Pos)))
+		el.Attr = append(el.Attr, xmlAttr("length", strconv.Itoa(t.Length)))
+	}
+
+	if err := e.EncodeToken(el); err != nil {
+		return err
+	}
+	for _, a := range t.Attrs {
+		attr := xml.StartElement{
+			Name: xml.Name{Local: "attr"},
+			Attr: []xml.Attr{xmlAttr("key", a.Key), xmlAttr("value", a.Value)},
+		}
+		if err := e.EncodeToken(attr); err != nil {
+			return err
+		}
+		if err := e.EncodeToken(attr.End()); err != nil {
+			return err
+		}
+	}
+	for _, c := range t.Children {
+		if err := writeXMLTree(e, c); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(el.End())
+}
+
+func xmlAttr(name, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
+
+// DiffDocument is a serializable result of the comparison of two trees
+// that refers to the nodes by ids
+type DiffDocument struct {
+	Matches []*DiffMatch  `json:"matches"`
+	Actions []*DiffAction `json:"actions"`
+}
+
+// DiffMatch is a mapping between nodes with ids Src and Dst
+type DiffMatch struct {
+	Src int `json:"src" xml:"src,attr"`
+	Dst int `json:"dest" xml:"dest,attr"`
+}
+
+// DiffAction is an action referring to the nodes by ids
+//
+// Tree is the id of a dst node for insert actions and the id of a src node for the others.
+// Parent and At are the id of the parent in dst tree and the position in it
+// and are used only by insert and move actions.
+// Key is the attribute of update-attr and delete-attr actions, Label is the new value of update-attr.
+// Segments of update actions are the difference between the old and the new label,
+// they are an extension of the format and aren't kept in the text format.
+type DiffAction struct {
+	Action   string         `json:"action"`
+	Tree     int            `json:"tree"`
+	Parent   int            `json:"parent,omitempty"`
+	At       int            `json:"at,omitempty"`
+	Key      string         `json:"key,omitempty"`
+	Label    string         `json:"label,omitempty"`
+	Segments []*DiffSegment `json:"segments,omitempty"`
+}
+
+// DiffSegment is a part of the difference between labels of an update action,
+// Type is one of "equal", "delete" and "insert"
+type DiffSegment struct {
+	Type string `json:"type" xml:"type,attr"`
+	Text string `json:"text" xml:",chardata"`
+}
+
+// MarshalJSON always writes the parent and the position of insert and move actions
+func (a *DiffAction) MarshalJSON() ([]byte, error) {
+	type plain DiffAction
+	v := struct {
+		*plain
+		Parent *int `json:"parent,omitempty"`
+		At     *int `json:"at,omitempty"`
+	}{plain: (*plain)(a)}
+	if a.hasPosition() {
+		v.Parent, v.At = &a.Parent, &a.At
+	}
+
+	return json.Marshal(v)
+}
+
+func (a *DiffAction) hasPosition() bool {
+	switch a.Action {
+	case Insert.String(), InsertTree.String(), Move.String():
+		return true
+	default:
+		return false
+	}
+}
+
+// NewDiffDocument converts mappings and actions to the document
+func NewDiffDocument(mappings []Mapping, actions []*Action) *DiffDocument {
+	d := &DiffDocument{
+		Matches: make([]*DiffMatch, len(mappings)),
+		Actions: make([]*DiffAction, len(actions)),
+	}
+
+	srcToDst := make(map[*Tree]*Tree, len(mappings))
+	for i, m := range mappings {
+		d.Matches[i] = &DiffMatch{Src: m[0].GetID(), Dst: m[1].GetID()}
+		srcToDst[m[0]] = m[1]
+	}
+
+	for i, a := range actions {
+		da := &DiffAction{Action: a.Type.String(), Tree: a.Node.GetID()}
+		switch a.Type {
+		case Insert, InsertTree:
+			if p := a.Node.GetParent(); p != nil {
+				da.Parent = p.GetID()
+				da.At = positionInParent(a.Node)
+			}
+		case Move:
+			if dst, ok := srcToDst[a.Node]; ok && dst.GetParent() != nil {
+				da.Parent = dst.GetParent().GetID()
+			}
+			da.At = a.Pos
+		case Update:
+			da.Label = a.Value
+			for _, s := range a.Segments() {
+				da.Segments = append(da.Segments, &DiffSegment{Type: s.Type.String(), Text: s.Text})
+			}
+		case UpdateAttr:
+			da.Key, da.Label = a.Key, a.Value
+		case DeleteAttr:
+			da.Key = a.Key
+		}
+		d.Actions[i] = da
+	}
+
+	return d
+}
+
+// ReadJSONDiff reads a diff in GumTree JSON format
+func ReadJSONDiff(r io.Reader) (*DiffDocument, error) {
+	var d DiffDocument
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// WriteJSON writes the diff in GumTree JSON format
+func (d *DiffDocument) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadXMLDiff reads a diff in GumTree XML format
+func ReadXMLDiff(r io.Reader) (*DiffDocument, error) {
+	var x struct {
+		Matches []*DiffMatch   `xml:"matches>match"`
+		Actions xmlDiffActions `xml:"actions"`
+	}
+	if err := xml.NewDecoder(r).Decode(&x); err != nil {
+		return nil, err
+	}
+
+	d := &DiffDocument{Matches: x.Matches}
+	for _, a := range x.Actions.List {
+		da := &DiffAction{Action: a.XMLName.Local, Tree: a.Tree, Key: a.Key, Label: a.Label, Segments: a.Segments}
+		if a.Parent != nil {
+			da.Parent = *a.Parent
+		}
+		if a.At != nil {
+			da.At = *a.At
+		}
+		d.Actions = append(d.Actions, da)
+	}
+
+	return d, nil
+}
+
+// WriteXML writes the diff in GumTree XML format
+func (d *DiffDocument) WriteXML(w io.Writer) error {
+	actions := make([]xmlDiffAction, len(d.Actions))
+	for i, a := range d.Actions {
+		actions[i] = xmlDiffAction{
+			XMLName:  xml.Name{Local: a.Action},
+			Tree:     a.Tree,
+			Key:      a.Key,
+			Label:    a.Label,
+			Segments: a.Segments,
+		}
+		if a.hasPosition() {
+			actions[i].Parent, actions[i].At = &d.Actions[i].Parent, &d.Actions[i].At
+		}
+	}
+
+	b, err := xml.MarshalIndent(struct {
+		XMLName xml.Name       `xml:"diff"`
+		Matches []*DiffMatch   `xml:"matches>match"`
+		Actions xmlDiffActions `xml:"actions"`
+	}{Matches: d.Matches, Actions: xmlDiffActions{actions}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+type xmlDiffActions struct {
+	List []xmlDiffAction `xml:",any"`
+}
+
+// action in xml uses element name as the type of the action
+type xmlDiffAction struct {
+	XMLName  xml.Name
+	Tree     int            `xml:"tree,attr"`
+	Parent   *int           `xml:"parent,attr"`
+	At       *int           `xml:"at,attr"`
+	Key      string         `xml:"key,attr,omitempty"`
+	Label    string         `xml:"label,attr,omitempty"`
+	Segments []*DiffSegment `xml:"segment"`
+}
+
+// WriteTextDiff writes mappings and actions in GumTree text format:
+//
+//	Match Type: label(id) to Type: label(id)
+//	Update Type: label(id) to label
+//	Update-attr Type: label(id) key to value
+//	Delete-attr Type: label(id) key
+//	Insert Type: label(id) into Type: label(id) at pos
+//	Move Type: label(id) into Type: label(id) at pos
+//	Delete Type: label(id)
+func WriteTextDiff(w io.Writer, mappings []Mapping, actions []*Action) error {
+	bw := bufio.NewWriter(w)
+
+	srcToDst := make(map[*Tree]*Tree, len(mappings))
+	for _, m := range mappings {
+		srcToDst[m[0]] = m[1]
+		fmt.Fprintf(bw, "Match %s to %s\n", textNode(m[0]), textNode(m[1]))
+	}
+
+	for _, a := range actions {
+		name := textActionNames[a.Type]
+		switch a.Type {
+		case Insert, InsertTree:
+			p := a.Node.GetParent()
+			if p == nil {
+				fmt.Fprintf(bw, "%s root %s\n", name, textNode(a.Node))
+				continue
+			}
+			fmt.Fprintf(bw, "%s %s into %s at %d\n", name, textNode(a.Node), textNode(p), positionInParent(a.Node))
+		case Move:
+			p := a.Parent
+			if dst, ok := srcToDst[a.Node]; ok && dst.GetParent() != nil {
+				p = dst.GetParent()
+			}
+			fmt.Fprintf(bw, "%s %s into %s at %d\n", name, textNode(a.Node), textNode(p), a.Pos)
+		case Update:
+			fmt.Fprintf(bw, "%s %s to %s\n", name, textNode(a.Node), a.Value)
+		case UpdateAttr:
+			fmt.Fprintf(bw, "%s %s %s to %s\n", name, textNode(a.Node), a.Key, a.Value)
+		case DeleteAttr:
+			fmt.Fprintf(bw, "%s %s %s\n", name, textNode(a.Node), a.Key)
+		default:
+			fmt.Fprintf(bw, "%s %s\n", name, textNode(a.Node))
+		}
+	}
+
+	return bw.Flush()
+}
+
+var textActionNames = map[Operation]string{
+	Delete:     "Delete",
+	DeleteTree: "Delete-tree",
+	Insert:     "Insert",
+	InsertTree: "Insert-tree",
+	Update:     "Update",
+	Move:       "Move",
+	UpdateAttr: "Update-attr",
+	DeleteAttr: "Delete-attr",
+}
+
+func textNode(t *Tree) string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s(%d)", t.Type, t.GetID())
+	}
+	return fmt.Sprintf("%s: %s(%d)", t.Type, t.Value, t.GetID())
+}
+
+var (
+	textMatchRe   = regexp.MustCompile(`^Match .*\((\d+)\) to .*\((\d+)\)$`)
+	textInsertRe  = regexp.MustCompile(`^(Insert|Insert-tree|Move) .*\((\d+)\) into .*\((\d+)\) at (\d+)$`)
+	textRootRe    = regexp.MustCompile(`^(Insert|Insert-tree) root .*\((\d+)\)$`)
+	textUpdateRe  = regexp.MustCompile(`^Update .*?\((\d+)\) to (.*)$`)
+	textAttrRe    = regexp.MustCompile(`^Update-attr .*?\((\d+)\) (\S+) to (.*)$`)
+	textDelAttrRe = regexp.MustCompile(`^Delete-attr .*\((\d+)\) (\S+)$`)
+	textDeleteRe  = regexp.MustCompile(`^(Delete|Delete-tree) .*\((\d+)\)$`)
+)
+
+// ReadTextDiff reads a diff in GumTree text format
+//
+// Labels of updated nodes must not contain "(number) to " sequence
+// and keys of updated attributes must not contain spaces
+func ReadTextDiff(r io.Reader) (*DiffDocument, error) {
+	d := &DiffDocument{}
+	actionNames := make(map[string]string, len(textActionNames))
+	for op, name := range textActionNames {
+		actionNames[name] = op.String()
+	}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+
+		if m := textMatchRe.FindStringSubmatch(line); m != nil {
+			d.Matches = append(d.Matches, &DiffMatch{Src: atoi(m[1]), Dst: atoi(m[2])})
+		} else if m := textInsertRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{
+				Action: actionNames[m[1]],
+				Tree:   atoi(m[2]),
+				Parent: atoi(m[3]),
+				At:     atoi(m[4]),
+			})
+		} else if m := textRootRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{Action: actionNames[m[1]], Tree: atoi(m[2])})
+		} else if m := textUpdateRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{Action: Update.String(), Tree: atoi(m[1]), Label: m[2]})
+		} else if m := textAttrRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{Action: UpdateAttr.String(), Tree: atoi(m[1]), Key: m[2], Label: m[3]})
+		} else if m := textDelAttrRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{Action: DeleteAttr.String(), Tree: atoi(m[1]), Key: m[2]})
+		} else if m := textDeleteRe.FindStringSubmatch(line); m != nil {
+			d.Actions = append(d.Actions, &DiffAction{Action: actionNames[m[1]], Tree: atoi(m[2])})
+		} else {
+			return nil, fmt.Errorf("unknown line: %s", line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// regular expressions guarantee correct numbers
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
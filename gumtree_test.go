@@ -0,0 +1,144 @@
+package gum
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gumtreeJSON = `{"root": {
+	"type": "15",
+	"typeLabel": "CompilationUnit",
+	"pos": "0",
+	"length": "20",
+	"children": [
+		{
+			"type": "42",
+			"label": "a",
+			"typeLabel": "SimpleName",
+			"pos": "7",
+			"length": 1,
+			"children": []
+		},
+		{
+			"typeLabel": "Block",
+			"attrs": [{"key": "scope", "value": "func"}],
+			"children": []
+		}
+	]
+}}`
+
+func TestReadTreeJSON(t *testing.T) {
+	tree, err := ReadTreeJSON(strings.NewReader(gumtreeJSON))
+	require.NoError(t, err)
+
+	assert.Equal(t, "CompilationUnit", tree.Type)
+	assert.Equal(t, 0, tree.Pos)
+	assert.Equal(t, 20, tree.Length)
+	assert.Equal(t, 2, tree.GetID())
+	require.Len(t, tree.Children, 2)
+	assert.Equal(t, "a", tree.Children[0].Value)
+	assert.Equal(t, 7, tree.Children[0].Pos)
+	assert.Equal(t, 1, tree.Children[0].Length)
+	assert.Equal(t, 0, tree.Children[0].GetID())
+	assert.Equal(t, 0, tree.Children[1].Length)
+	assert.Equal(t, []Attr{{"scope", "func"}}, tree.Children[1].Attrs)
+}
+
+func TestTreeFormatsRoundTrip(t *testing.T) {
+	tree, err := ReadTreeJSON(strings.NewReader(gumtreeJSON))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTreeJSON(&buf, tree))
+	fromJSON, err := ReadTreeJSON(&buf)
+	require.NoError(t, err)
+	assert.True(t, tree.IsIsomorphicTo(fromJSON))
+	assert.Equal(t, tree.Children[0].Pos, fromJSON.Children[0].Pos)
+	assert.Equal(t, tree.Children[1].Attrs, fromJSON.Children[1].Attrs)
+
+	buf.Reset()
+	require.NoError(t, WriteTreeXML(&buf, tree))
+	assert.Contains(t, buf.String(), `<tree label="a" typeLabel="SimpleName" pos="7" length="1"></tree>`)
+	fromXML, err := ReadTreeXML(&buf)
+	require.NoError(t, err)
+	assert.True(t, tree.IsIsomorphicTo(fromXML))
+	assert.Equal(t, tree.Length, fromXML.Length)
+	assert.Equal(t, tree.Children[1].Attrs, fromXML.Children[1].Attrs)
+}
+
+func TestDiffFormats(t *testing.T) {
+	src, err := treeFromJSON(`{"root": {"typeLabel": "Block", "attrs": [{"key": "scope", "value": "func"}, {"key": "doc", "value": "x"}], "children": [
+		{"typeLabel": "Name", "label": "a", "children": []},
+		{"typeLabel": "Name", "label": "b", "children": []}
+	]}}`)
+	require.NoError(t, err)
+	dst, err := treeFromJSON(`{"root": {"typeLabel": "Block", "attrs": [{"key": "scope", "value": "file"}], "children": [
+		{"typeLabel": "Name", "label": "c", "children": []},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "b", "children": []}
+		]}
+	]}}`)
+	require.NoError(t, err)
+
+	mappings := []Mapping{
+		{src, dst},
+		{src.Children[0], dst.Children[0]},
+		{src.Children[1], dst.Children[1].Children[0]},
+	}
+	actions := Patch(src, dst, mappings)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTextDiff(&buf, mappings, actions))
+	assert.Equal(t, `Match Block(2) to Block(3)
+Match Name: a(0) to Name: c(0)
+Match Name: b(1) to Name: b(1)
+Update-attr Block(2) scope to file
+Delete-attr Block(2) doc
+Update Name: a(0) to c
+Insert Call(2) into Block(3) at 1
+Move Name: b(1) into Call(2) at 0
+`, buf.String())
+
+	expected := &DiffDocument{
+		Matches: []*DiffMatch{{Src: 2, Dst: 3}, {Src: 0, Dst: 0}, {Src: 1, Dst: 1}},
+		Actions: []*DiffAction{
+			{Action: "update-attr", Tree: 2, Key: "scope", Label: "file"},
+			{Action: "delete-attr", Tree: 2, Key: "doc"},
+			{Action: "update", Tree: 0, Label: "c", Segments: []*DiffSegment{{"delete", "a"}, {"insert", "c"}}},
+			{Action: "insert", Tree: 2, Parent: 3, At: 1},
+			{Action: "move", Tree: 1, Parent: 2, At: 0},
+		},
+	}
+
+	fromText, err := ReadTextDiff(&buf)
+	require.NoError(t, err)
+	// the text format doesn't keep segments
+	require.Nil(t, fromText.Actions[2].Segments)
+	fromText.Actions[2].Segments = expected.Actions[2].Segments
+	assert.Equal(t, expected, fromText)
+
+	d := NewDiffDocument(mappings, actions)
+	assert.Equal(t, expected, d)
+
+	buf.Reset()
+	require.NoError(t, d.WriteJSON(&buf))
+	fromJSON, err := ReadJSONDiff(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, expected, fromJSON)
+
+	buf.Reset()
+	require.NoError(t, d.WriteXML(&buf))
+	assert.Contains(t, buf.String(), `<update tree="0" label="c">
+      <segment type="delete">a</segment>
+      <segment type="insert">c</segment>
+    </update>`)
+	assert.Contains(t, buf.String(), `<update-attr tree="2" key="scope" label="file"></update-attr>`)
+	assert.Contains(t, buf.String(), `<delete-attr tree="2" key="doc"></delete-attr>`)
+	fromXML, err := ReadXMLDiff(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, expected, fromXML)
+}
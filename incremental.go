@@ -0,0 +1,481 @@
+package gum
+
+import "sort"
+
+// Range is a span of bytes in the source, End is exclusive.
+// Empty range marks a position where something was removed.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Incremental keeps mappings between src and dst while dst is edited
+//
+// Update doesn't visit unchanged subtrees of dst: they are paired with subtrees
+// of the previous version by position in their parents and mappings of their nodes
+// are resolved through the history of updates on lookups.
+// Only the nodes inside of the changed ranges are matched again against
+// the unmapped nodes of src next to them.
+type Incremental struct {
+	m        *Matcher
+	src, dst *Tree
+	// mappings between src and the version of dst before the edits
+	mappings *mappingStore
+	edits    []*incrementalEdit
+	// unmapped nodes of src by their parents
+	unmapped map[*Tree]map[*Tree]bool
+}
+
+// NewIncremental matches the trees and returns the state to update mappings after edits of dst
+func NewIncremental(src, dst *Tree) *Incremental {
+	return NewMatcher().NewIncremental(src, dst)
+}
+
+// NewIncremental matches the trees and returns the state to update mappings after edits of dst
+func (m *Matcher) NewIncremental(src, dst *Tree) *Incremental {
+	inc := &Incremental{m: m, src: src}
+	inc.reset(dst, m.Match(src, dst))
+	return inc
+}
+
+func (inc *Incremental) reset(dst *Tree, mappings []Mapping) {
+	inc.dst = dst
+	inc.mappings = newMappingStore()
+	for _, m := range mappings {
+		inc.mappings.Link(m[0], m[1])
+	}
+	inc.edits = nil
+
+	inc.unmapped = make(map[*Tree]map[*Tree]bool)
+	for _, s := range PreOrder(inc.src) {
+		if _, ok := inc.mappings.GetDst(s); !ok {
+			inc.setMapped(s, false)
+		}
+	}
+}
+
+// Update replaces dst with its new version.
+//
+// changed are the ranges of the new version that differ from the previous one,
+// both versions must have positions with children ordered by them.
+// Nodes outside of the changed ranges are expected to be unchanged.
+// The trees must be refreshed and must not share nodes.
+func (inc *Incremental) Update(dst *Tree, changed []Range) {
+	e := &incrementalEdit{
+		spine:     make(map[*Tree]*spineNode),
+		spinePrev: make(map[*Tree]*spineNode),
+		dirty:     make(map[*Tree]bool),
+		removed:   make(map[*Tree]bool),
+		links:     newMappingStore(),
+		freed:     make(map[*Tree]bool),
+	}
+	if !e.align(inc.dst, dst, changed) {
+		inc.reset(dst, inc.m.Match(inc.src, dst))
+		return
+	}
+
+	// nodes of src mapped to removed nodes become unmapped
+	last := len(inc.edits) - 1
+	for _, r := range e.removedList {
+		for _, o := range PreOrder(r) {
+			if s, ok := inc.srcAt(last, o); ok {
+				e.freed[s] = true
+				inc.setMapped(s, false)
+			}
+		}
+	}
+
+	inc.edits = append(inc.edits, e)
+	inc.dst = dst
+	if len(e.dirtyList) > 0 {
+		inc.rematch(e)
+	}
+}
+
+// rematch matches changed subtrees against unmapped subtrees of src
+// that were mapped to removed nodes or are children of the parents of changes
+func (inc *Incremental) rematch(e *incrementalEdit) {
+	last := len(inc.edits) - 1
+	roots := make(map[*Tree]bool)
+	for s := range e.freed {
+		roots[s] = true
+	}
+	for _, d := range e.dirtyList {
+		if s, ok := inc.srcAt(last, d.parent); ok {
+			for c := range inc.unmapped[s] {
+				roots[c] = true
+			}
+		}
+	}
+
+	var sorted []*Tree
+	for s := range roots {
+		if !hasAncestorIn(s, roots) {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].id < sorted[j].id })
+
+	var unmapped []*Tree
+	for _, s := range sorted {
+		if !inc.collectUnmapped(s, &unmapped) {
+			unmapped = append(unmapped, s)
+		}
+	}
+	if len(unmapped) == 0 {
+		return
+	}
+
+	srcForest, srcIds := newForest(unmapped)
+	dstForest, dstIds := newForest(e.dirtyList)
+	for _, mp := range inc.m.Match(srcForest, dstForest) {
+		if mp[0] == srcForest || mp[1] == dstForest {
+			continue
+		}
+		s, d := srcIds[mp[0].id], dstIds[mp[1].id]
+		e.links.Link(s, d)
+		inc.setMapped(s, true)
+	}
+}
+
+func hasAncestorIn(t *Tree, trees map[*Tree]bool) bool {
+	for p := t.parent; p != nil; p = p.parent {
+		if trees[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// collectUnmapped adds the largest subtrees without mapped nodes,
+// returns false if the whole tree is unmapped
+func (inc *Incremental) collectUnmapped(t *Tree, trees *[]*Tree) bool {
+	hasMapped := !inc.unmapped[t.parent][t]
+	unmapped := make([]bool, len(t.Children))
+	for i, c := range t.Children {
+		if inc.collectUnmapped(c, trees) {
+			hasMapped = true
+		} else {
+			unmapped[i] = true
+		}
+	}
+	if !hasMapped {
+		return false
+	}
+
+	for i, c := range t.Children {
+		if unmapped[i] {
+			*trees = append(*trees, c)
+		}
+	}
+	return true
+}
+
+func (inc *Incremental) setMapped(s *Tree, mapped bool) {
+	children := inc.unmapped[s.parent]
+	if mapped {
+		delete(children, s)
+		return
+	}
+	if children == nil {
+		children = make(map[*Tree]bool)
+		inc.unmapped[s.parent] = children
+	}
+	children[s] = true
+}
+
+// GetSrc returns the node of src mapped to the node of dst
+func (inc *Incremental) GetSrc(dst *Tree) (*Tree, bool) {
+	return inc.srcAt(len(inc.edits)-1, dst)
+}
+
+// srcAt returns the node of src mapped to the node of dst after the edit with the index
+func (inc *Incremental) srcAt(edit int, d *Tree) (*Tree, bool) {
+	for ; edit >= 0; edit-- {
+		e := inc.edits[edit]
+		if s, ok := e.links.GetSrc(d); ok {
+			return s, true
+		}
+		var ok bool
+		if d, ok = e.prevNode(d); !ok {
+			return nil, false
+		}
+	}
+
+	return inc.mappings.GetSrc(d)
+}
+
+// GetDst returns the node of dst mapped to the node of src
+func (inc *Incremental) GetDst(src *Tree) (*Tree, bool) {
+	for i := len(inc.edits) - 1; i >= 0; i-- {
+		e := inc.edits[i]
+		if d, ok := e.links.GetDst(src); ok {
+			return inc.forward(i+1, d)
+		}
+		if e.freed[src] {
+			return nil, false
+		}
+	}
+
+	d, ok := inc.mappings.GetDst(src)
+	if !ok {
+		return nil, false
+	}
+	return inc.forward(0, d)
+}
+
+// forward returns the node of dst for the node of the version before the edit with the index
+func (inc *Incremental) forward(edit int, d *Tree) (*Tree, bool) {
+	for _, e := range inc.edits[edit:] {
+		var ok bool
+		if d, ok = e.nextNode(d); !ok {
+			return nil, false
+		}
+	}
+
+	return d, true
+}
+
+// Mappings returns all mappings between src and dst.
+//
+// It's linear in the size of the trees and drops the history of updates
+// which makes lookups in the following updates cheaper.
+func (inc *Incremental) Mappings() []Mapping {
+	if len(inc.edits) == 0 {
+		return inc.mappings.ToList()
+	}
+
+	mappings := newMappingStore()
+	for _, d := range PreOrder(inc.dst) {
+		if s, ok := inc.GetSrc(d); ok {
+			mappings.Link(s, d)
+		}
+	}
+	inc.mappings, inc.edits = mappings, nil
+
+	return mappings.ToList()
+}
+
+// incrementalEdit pairs nodes of the previous and the new versions of dst
+//
+// Only the nodes with changed descendants are paired explicitly,
+// children outside of the changes are paired by their index in the parents.
+type incrementalEdit struct {
+	// paired ancestors of changes by the new and the previous nodes
+	spine, spinePrev map[*Tree]*spineNode
+	// new subtrees without pairs
+	dirty     map[*Tree]bool
+	dirtyList []*Tree
+	// previous subtrees without pairs
+	removed     map[*Tree]bool
+	removedList []*Tree
+	// mappings between src and the new subtrees
+	links *mappingStore
+	// nodes of src mapped to the removed subtrees
+	freed map[*Tree]bool
+}
+
+// spineNode is a pair of nodes with changed descendants.
+// Children [lo, hi) of the new node replaced children [lo, prevHi) of the previous one.
+type spineNode struct {
+	prev, t        *Tree
+	lo, hi, prevHi int
+}
+
+func (n *spineNode) prevIndex(i int) (int, bool) {
+	switch {
+	case i < n.lo:
+		return i, true
+	case i >= n.hi:
+		return i - n.hi + n.prevHi, true
+	case n.hi == n.prevHi:
+		return i, true
+	}
+	return 0, false
+}
+
+func (n *spineNode) nextIndex(i int) (int, bool) {
+	switch {
+	case i < n.lo:
+		return i, true
+	case i >= n.prevHi:
+		return i - n.prevHi + n.hi, true
+	case n.hi == n.prevHi:
+		return i, true
+	}
+	return 0, false
+}
+
+// align pairs the nodes with changes, returns false if the roots can't be paired
+func (e *incrementalEdit) align(prev, t *Tree, changed []Range) bool {
+	if prev.Type != t.Type {
+		return false
+	}
+
+	children, prevChildren := t.Children, prev.Children
+	var ranges []Range
+	for _, r := range changed {
+		if touches(t, r) || isRoot(t) {
+			ranges = append(ranges, r)
+		}
+	}
+
+	// the node is an ancestor of changes, keep it and compare children
+	n := &spineNode{prev: prev, t: t}
+	if len(ranges) == 0 {
+		if len(children) != len(prevChildren) || !prev.IsIsomorphicTo(t) {
+			return false
+		}
+		n.lo, n.hi, n.prevHi = len(children), len(children), len(prevChildren)
+		e.spine[t], e.spinePrev[prev] = n, n
+		return true
+	}
+
+	// children before and after the changes are unchanged
+	start, end := ranges[0].Start, ranges[0].End
+	for _, r := range ranges {
+		if r.Start < start {
+			start = r.Start
+		}
+		if r.End > end {
+			end = r.End
+		}
+	}
+	n.lo = sort.Search(len(children), func(i int) bool { return children[i].End() > start })
+	n.hi = n.lo + sort.Search(len(children)-n.lo, func(i int) bool { return children[n.lo+i].Pos >= end })
+	n.prevHi = len(prevChildren) - (len(children) - n.hi)
+	if n.prevHi < n.lo ||
+		n.lo > 0 && !prevChildren[n.lo-1].IsIsomorphicTo(children[n.lo-1]) ||
+		n.hi < len(children) && !prevChildren[n.prevHi].IsIsomorphicTo(children[n.hi]) {
+		return false
+	}
+	e.spine[t], e.spinePrev[prev] = n, n
+
+	if n.hi-n.lo != n.prevHi-n.lo {
+		for _, c := range children[n.lo:n.hi] {
+			e.setDirty(c)
+		}
+		for _, c := range prevChildren[n.lo:n.prevHi] {
+			e.setRemoved(c)
+		}
+		return true
+	}
+
+	// the same number of children, only the touched ones are compared
+	for _, i := range touchedChildren(t, n.lo, n.hi, ranges) {
+		if !e.align(prevChildren[i], children[i], ranges) {
+			e.setDirty(children[i])
+			e.setRemoved(prevChildren[i])
+		}
+	}
+	return true
+}
+
+func (e *incrementalEdit) setDirty(t *Tree) {
+	e.dirty[t] = true
+	e.dirtyList = append(e.dirtyList, t)
+}
+
+func (e *incrementalEdit) setRemoved(t *Tree) {
+	e.removed[t] = true
+	e.removedList = append(e.removedList, t)
+}
+
+// prevNode returns the node of the previous version paired with the new node
+func (e *incrementalEdit) prevNode(t *Tree) (*Tree, bool) {
+	if n, ok := e.spine[t]; ok {
+		return n.prev, true
+	}
+	if e.dirty[t] || t.parent == nil {
+		return nil, false
+	}
+
+	i := childIndex(t.parent, t)
+	if n, ok := e.spine[t.parent]; ok {
+		if i, ok = n.prevIndex(i); !ok {
+			return nil, false
+		}
+		return n.prev.Children[i], true
+	}
+	p, ok := e.prevNode(t.parent)
+	if !ok {
+		return nil, false
+	}
+	return p.Children[i], true
+}
+
+// nextNode returns the new node paired with the node of the previous version
+func (e *incrementalEdit) nextNode(t *Tree) (*Tree, bool) {
+	if n, ok := e.spinePrev[t]; ok {
+		return n.t, true
+	}
+	if e.removed[t] || t.parent == nil {
+		return nil, false
+	}
+
+	i := childIndex(t.parent, t)
+	if n, ok := e.spinePrev[t.parent]; ok {
+		if i, ok = n.nextIndex(i); !ok {
+			return nil, false
+		}
+		return n.t.Children[i], true
+	}
+	p, ok := e.nextNode(t.parent)
+	if !ok {
+		return nil, false
+	}
+	return p.Children[i], true
+}
+
+// touchedChildren returns indexes of the children in [lo, hi) touched by the ranges
+func touchedChildren(t *Tree, lo, hi int, ranges []Range) []int {
+	var indexes []int
+	children := t.Children[lo:hi]
+	for _, r := range ranges {
+		i := sort.Search(len(children), func(i int) bool { return children[i].End() > r.Start })
+		for ; i < len(children) && touches(children[i], r); i++ {
+			indexes = append(indexes, lo+i)
+		}
+	}
+
+	sort.Ints(indexes)
+	unique := indexes[:0]
+	for i, idx := range indexes {
+		if i == 0 || idx != indexes[i-1] {
+			unique = append(unique, idx)
+		}
+	}
+	return unique
+}
+
+func touches(t *Tree, r Range) bool {
+	if r.Start == r.End {
+		return t.Pos < r.Start && r.Start < t.End()
+	}
+	return t.Pos < r.End && r.Start < t.End()
+}
+
+// childIndex finds the child by position falling back to the linear search
+func childIndex(t, child *Tree) int {
+	i := sort.Search(len(t.Children), func(i int) bool { return t.Children[i].Pos >= child.Pos })
+	for ; i < len(t.Children) && t.Children[i].Pos == child.Pos; i++ {
+		if t.Children[i] == child {
+			return i
+		}
+	}
+	return getChildPosition(t, child)
+}
+
+// newForest joins copies of the trees under a fake root
+// and returns index of the original trees by id
+func newForest(trees []*Tree) (*Tree, map[int]*Tree) {
+	ids := make(map[int]*Tree)
+	root := &Tree{Type: "incremental-forest", id: -1}
+	for _, t := range trees {
+		putTrees(ids, t)
+		root.Children = append(root.Children, t.clone())
+	}
+	root.refresh(nil)
+
+	return root, ids
+}
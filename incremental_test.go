@@ -0,0 +1,222 @@
+package gum
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalUpdate(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(dst *Tree) *Tree
+	}{
+		{"update", func(dst *Tree) *Tree {
+			n := getChild(dst, 0, 1)
+			n.Value = "renamed"
+			return n
+		}},
+		{"insert", func(dst *Tree) *Tree {
+			p := getChild(dst, 0)
+			n := &Tree{Type: "SimpleName", Value: "inserted"}
+			p.Children = append(p.Children[:2], append([]*Tree{n}, p.Children[2:]...)...)
+			return n
+		}},
+		{"delete", func(dst *Tree) *Tree {
+			p := getChild(dst, 0)
+			p.Children = append(p.Children[:1], p.Children[2:]...)
+			return p.Children[1]
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src, prevDst := readFixtures("testdata/paper/src.json", "testdata/paper/dst.json")
+			_, dst := readFixtures("testdata/paper/src.json", "testdata/paper/dst.json")
+			setTestPositions(prevDst, 0)
+
+			edited := c.edit(dst)
+			dst.Refresh()
+			setTestPositions(dst, 0)
+			changed := []Range{{edited.Pos, edited.End()}}
+			if c.name == "delete" {
+				changed = []Range{{edited.Pos, edited.Pos}}
+			}
+
+			prev := Match(src, prevDst)
+			inc := NewIncremental(src, prevDst)
+			inc.Update(dst, changed)
+			for _, m := range Match(src, dst) {
+				s, ok := inc.GetSrc(m[1])
+				assert.True(t, ok)
+				assert.Equal(t, m[0], s)
+				d, ok := inc.GetDst(m[0])
+				assert.True(t, ok)
+				assert.Equal(t, m[1], d)
+			}
+			mappings := inc.Mappings()
+			assert.ElementsMatch(t, mappingIDs(Match(src, dst)), mappingIDs(mappings))
+
+			// unchanged nodes keep mappings
+			srcToPrev := make(map[*Tree]*Tree)
+			for _, m := range prev {
+				srcToPrev[m[0]] = m[1]
+			}
+			for _, m := range mappings {
+				p, ok := srcToPrev[m[0]]
+				require.True(t, ok)
+				if m[1] != edited && !isRoot(m[1]) {
+					assert.Equal(t, p.Type, m[1].Type)
+				}
+			}
+		})
+	}
+}
+
+func TestIncrementalRootChanged(t *testing.T) {
+	src, prevDst := readFixtures("testdata/paper/src.json", "testdata/paper/dst.json")
+	_, dst := readFixtures("testdata/paper/src.json", "testdata/paper/dst.json")
+	dst.Type = "Other"
+	dst.Refresh()
+
+	inc := NewIncremental(src, prevDst)
+	inc.Update(dst, nil)
+	assert.ElementsMatch(t, mappingIDs(Match(src, dst)), mappingIDs(inc.Mappings()))
+}
+
+func TestIncrementalUpdates(t *testing.T) {
+	src, prevDst, dst := benchTree(10), benchTree(10), benchTree(10)
+	inserted, changed := insertStatement(dst, 5)
+	renamed := getChild(src, 2, 0)
+	renamed.Value = "renamed"
+	src.Refresh()
+
+	inc := NewIncremental(src, prevDst)
+	inc.Update(dst, changed)
+	_, ok := inc.GetSrc(inserted)
+	assert.False(t, ok)
+	_, ok = inc.GetDst(renamed)
+	assert.True(t, ok)
+
+	// revert the insertion without reading mappings in between
+	reverted := benchTree(10)
+	inc.Update(reverted, []Range{{inserted.Pos, inserted.Pos}})
+	assert.ElementsMatch(t, mappingIDs(Match(src, reverted)), mappingIDs(inc.Mappings()))
+	assert.Len(t, inc.edits, 0)
+}
+
+// TestIncrementalUpdateIsLocal checks that the same edit touches
+// the same number of nodes in trees of different sizes
+func TestIncrementalUpdateIsLocal(t *testing.T) {
+	type stats struct{ spine, dirty, removed, links int }
+	update := func(n int, edit func(dst *Tree) []Range) stats {
+		src, prevDst, dst := benchTree(n), benchTree(n), benchTree(n)
+		changed := edit(dst)
+		inc := NewIncremental(src, prevDst)
+		inc.Update(dst, changed)
+		require.Len(t, inc.edits, 1)
+		e := inc.edits[0]
+		return stats{len(e.spine), len(e.dirty), len(e.removed), e.links.Size()}
+	}
+
+	edits := map[string]func(dst *Tree) []Range{
+		"rename": func(dst *Tree) []Range {
+			n := len(dst.Children) / 2
+			edited := getChild(dst, n, 0)
+			edited.Value = "renamed"
+			dst.Refresh()
+			setTestPositions(dst, 0)
+			return []Range{{edited.Pos, edited.End()}}
+		},
+		"insert": func(dst *Tree) []Range {
+			_, changed := insertStatement(dst, len(dst.Children)/2)
+			return changed
+		},
+	}
+	for name, edit := range edits {
+		t.Run(name, func(t *testing.T) {
+			small := update(100, edit)
+			assert.Equal(t, small, update(3000, edit))
+			assert.True(t, small.spine <= 3, "spine of %d nodes", small.spine)
+		})
+	}
+}
+
+// insertStatement adds a statement before the statement with the index
+func insertStatement(dst *Tree, i int) (*Tree, []Range) {
+	n := &Tree{Type: "Assign", Children: []*Tree{
+		{Type: "Name", Value: "inserted"},
+		{Type: "Literal", Value: "1"},
+	}}
+	dst.Children = append(dst.Children[:i], append([]*Tree{n}, dst.Children[i:]...)...)
+	dst.Refresh()
+	setTestPositions(dst, 0)
+	return n, []Range{{n.Pos, n.End()}}
+}
+
+// setTestPositions gives every leaf the length of its label plus a separator
+func setTestPositions(t *Tree, pos int) int {
+	t.Pos = pos
+	if t.isLeaf() {
+		pos += len(t.Value) + 1
+	}
+	for _, c := range t.Children {
+		pos = setTestPositions(c, pos)
+	}
+	t.Length = pos - t.Pos
+	return pos
+}
+
+func mappingIDs(mappings []Mapping) [][2]int {
+	ids := make([][2]int, len(mappings))
+	for i, m := range mappings {
+		ids[i] = [2]int{m[0].GetID(), m[1].GetID()}
+	}
+	return ids
+}
+
+// benchTree builds a tree of n statements with a few nodes each
+func benchTree(n int) *Tree {
+	root := &Tree{Type: "Block"}
+	for i := 0; i < n; i++ {
+		root.Children = append(root.Children, &Tree{Type: "Assign", Children: []*Tree{
+			{Type: "Name", Value: fmt.Sprintf("v%d", i)},
+			{Type: "Call", Children: []*Tree{
+				{Type: "Name", Value: "f"},
+				{Type: "Literal", Value: strconv.Itoa(i)},
+			}},
+		}})
+	}
+	root.Refresh()
+	setTestPositions(root, 0)
+	return root
+}
+
+// BenchmarkIncrementalUpdate inserts and removes a statement in the middle of the tree,
+// the time of the update doesn't depend on the size of the tree
+func BenchmarkIncrementalUpdate(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		src, prevDst, dst := benchTree(n), benchTree(n), benchTree(n)
+		inserted, changed := insertStatement(dst, n/2)
+		removed := []Range{{inserted.Pos, inserted.Pos}}
+
+		b.Run(fmt.Sprintf("full-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Match(src, dst)
+			}
+		})
+		b.Run(fmt.Sprintf("incremental-%d", n), func(b *testing.B) {
+			inc := NewIncremental(src, prevDst)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				inc.Update(dst, changed)
+				inc.Update(prevDst, removed)
+				// the versions alternate, without the history the state is the initial one
+				inc.edits = inc.edits[:0]
+			}
+		})
+	}
+}
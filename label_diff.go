@@ -0,0 +1,135 @@
+package gum
+
+import (
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// SegmentType describes how a part of a label changed
+type SegmentType int8
+
+const (
+	// SegmentEqual is a part of both labels
+	SegmentEqual SegmentType = iota
+	// SegmentDelete is a part of the old label only
+	SegmentDelete
+	// SegmentInsert is a part of the new label only
+	SegmentInsert
+)
+
+func (t SegmentType) String() string {
+	switch t {
+	case SegmentEqual:
+		return "equal"
+	case SegmentDelete:
+		return "delete"
+	case SegmentInsert:
+		return "insert"
+	default:
+		return "unknown segment"
+	}
+}
+
+// Segment is a part of the difference between two labels
+type Segment struct {
+	Type SegmentType
+	Text string
+}
+
+// Segments returns the difference between the old and the new label of an Update action
+// or nil for other actions
+func (a *Action) Segments() []Segment {
+	if a.Type != Update {
+		return nil
+	}
+	return LabelDiff(a.Node.Value, a.Value)
+}
+
+// LabelDiff returns the word-level difference between labels
+//
+// Labels are split into words, runs of whitespace and single other characters,
+// so a changed word of a comment or a string literal is reported as a whole.
+// Joining segments except SegmentInsert gives the label before,
+// joining segments except SegmentDelete gives the label after.
+func LabelDiff(before, after string) []Segment {
+	// every word is encoded as a rune to diff sequences of words
+	tokens := make(map[string]rune)
+	text := make(map[rune]string)
+	toRunes := func(s string) []rune {
+		var rs []rune
+		for _, w := range splitWords(s) {
+			r, ok := tokens[w]
+			if !ok {
+				r = rune(len(tokens) + 1)
+				// surrogates can't be encoded in a string
+				if r >= 0xD800 {
+					r += 0x800
+				}
+				tokens[w] = r
+				text[r] = w
+			}
+			rs = append(rs, r)
+		}
+		return rs
+	}
+	beforeRunes, afterRunes := toRunes(before), toRunes(after)
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMainRunes(beforeRunes, afterRunes, false))
+
+	var segments []Segment
+	for _, d := range diffs {
+		var s Segment
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			s.Type = SegmentDelete
+		case diffmatchpatch.DiffInsert:
+			s.Type = SegmentInsert
+		}
+		for _, r := range d.Text {
+			s.Text += text[r]
+		}
+		if s.Text == "" {
+			continue
+		}
+		// the cleanup may leave neighbours of the same type
+		if n := len(segments); n > 0 && segments[n-1].Type == s.Type {
+			segments[n-1].Text += s.Text
+			continue
+		}
+		segments = append(segments, s)
+	}
+
+	return segments
+}
+
+// splitWords splits the string into words, runs of whitespace and other characters
+func splitWords(s string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		default:
+			return 0
+		}
+	}
+
+	var words []string
+	start, prev := 0, -1
+	for i, r := range s {
+		c := class(r)
+		if i > start && (c != prev || c == 0) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+
+	return words
+}
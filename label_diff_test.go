@@ -0,0 +1,57 @@
+package gum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	segments := LabelDiff(`"failed to open file %s"`, `"failed to read file %s"`)
+	assert.Equal([]Segment{
+		{SegmentEqual, `"failed to `},
+		{SegmentDelete, "open"},
+		{SegmentInsert, "read"},
+		{SegmentEqual, ` file %s"`},
+	}, segments)
+
+	segments = LabelDiff("// Close closes the file", "// Close closes the file and the connection.")
+	assert.Equal([]Segment{
+		{SegmentEqual, "// Close closes the file"},
+		{SegmentInsert, " and the connection."},
+	}, segments)
+
+	assert.Equal([]Segment{{SegmentDelete, "x"}, {SegmentInsert, "y"}}, LabelDiff("x", "y"))
+	assert.Equal([]Segment{{SegmentInsert, "new"}}, LabelDiff("", "new"))
+	assert.Empty(LabelDiff("", ""))
+}
+
+func TestLabelDiffJoins(t *testing.T) {
+	old, new := "counter := counter + 1 // increment", "total := total + step // increment by step"
+	var gotOld, gotNew string
+	for _, s := range LabelDiff(old, new) {
+		if s.Type != SegmentInsert {
+			gotOld += s.Text
+		}
+		if s.Type != SegmentDelete {
+			gotNew += s.Text
+		}
+	}
+	assert.Equal(t, old, gotOld)
+	assert.Equal(t, new, gotNew)
+}
+
+func TestActionSegments(t *testing.T) {
+	node := &Tree{Type: "BasicLit", Value: `"hello world"`}
+	a := newUpdate(node, `"hello gum"`)
+	assert.Equal(t, []Segment{
+		{SegmentEqual, `"hello `},
+		{SegmentDelete, "world"},
+		{SegmentInsert, "gum"},
+		{SegmentEqual, `"`},
+	}, a.Segments())
+
+	assert.Nil(t, newDelete(node).Segments())
+}
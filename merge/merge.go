@@ -0,0 +1,395 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/smacker/gum"
+)
+
+// Version is one of the versions of a file taking part in the merge
+type Version struct {
+	Content []byte
+	// Tree of the content, positions of the nodes are required
+	Tree *gum.Tree
+}
+
+// CheckPositions returns an error if the tree doesn't cover the content,
+// for example if its positions refer to another file or the parser doesn't set them
+func (v *Version) CheckPositions() error {
+	root := v.Tree
+	if root.Pos < 0 || root.Length <= 0 || root.End() > len(v.Content) {
+		return fmt.Errorf("positions of the tree %d:%d don't cover the content of %d bytes", root.Pos, root.End(), len(v.Content))
+	}
+	return nil
+}
+
+// Conflict is a subtree changed differently in ours and theirs versions
+type Conflict struct {
+	// Nil if the subtree doesn't exist in the version
+	Base   *gum.Tree
+	Ours   *gum.Tree
+	Theirs *gum.Tree
+}
+
+// Result of the three-way merge
+type Result struct {
+	// Content of the merged file.
+	// Conflicting subtrees are surrounded by conflict markers.
+	Content   []byte
+	Conflicts []*Conflict
+}
+
+// Merge does three-way merge of two versions of a file with their common ancestor
+//
+// Changes are merged at the level of subtrees:
+// when only one side changed a subtree the change is taken,
+// when both sides changed it, children of the subtree are merged recursively.
+// The result is built from the source text of the versions,
+// so trees of all versions must pass CheckPositions.
+func Merge(base, ours, theirs *Version) *Result {
+	m := &merger{
+		base:          base,
+		ours:          ours,
+		theirs:        theirs,
+		ourMappings:   newMappings(gum.Match(base.Tree, ours.Tree)),
+		theirMappings: newMappings(gum.Match(base.Tree, theirs.Tree)),
+	}
+
+	root := ours.Tree
+	var content []byte
+	content = append(content, ours.Content[:root.Pos]...)
+	p := m.mergeNode(base.Tree, ours.Tree, theirs.Tree)
+	if p.conflict != nil {
+		p = m.conflictPiece(p.conflict)
+	}
+	content = append(content, p.text...)
+	content = append(content, ours.Content[root.End():]...)
+
+	return &Result{Content: content, Conflicts: p.conflicts}
+}
+
+// Conflict markers
+const (
+	OursMarker   = "<<<<<<< ours"
+	SepMarker    = "======="
+	TheirsMarker = ">>>>>>> theirs"
+)
+
+type mappings struct {
+	srcs map[*gum.Tree]*gum.Tree
+	dsts map[*gum.Tree]*gum.Tree
+}
+
+func newMappings(ms []gum.Mapping) *mappings {
+	m := &mappings{
+		srcs: make(map[*gum.Tree]*gum.Tree, len(ms)),
+		dsts: make(map[*gum.Tree]*gum.Tree, len(ms)),
+	}
+	for _, mp := range ms {
+		m.srcs[mp[0]] = mp[1]
+		m.dsts[mp[1]] = mp[0]
+	}
+	return m
+}
+
+type merger struct {
+	base   *Version
+	ours   *Version
+	theirs *Version
+
+	// base to ours
+	ourMappings *mappings
+	// base to theirs
+	theirMappings *mappings
+}
+
+// piece of the merged text
+type piece struct {
+	text []byte
+	// not nil if the piece couldn't be merged
+	conflict *Conflict
+	// conflicts surrounded by markers inside of the text
+	conflicts []*Conflict
+	// version and node the piece originates from, used to take separators between siblings
+	version *Version
+	node    *gum.Tree
+}
+
+// labelChanged returns true if the label or attributes of the node differ from the other version
+func labelChanged(n, other *gum.Tree) bool {
+	return n.Value != other.Value || !n.EqualAttrs(other)
+}
+
+// mergeNode merges base, ours and theirs variants of the same node
+func (m *merger) mergeNode(b, o, t *gum.Tree) *piece {
+	if o.IsIsomorphicTo(b) {
+		return m.nodePiece(m.theirs, t)
+	}
+	if t.IsIsomorphicTo(b) || o.IsIsomorphicTo(t) {
+		return m.nodePiece(m.ours, o)
+	}
+
+	conflict := &piece{
+		conflict: &Conflict{Base: b, Ours: o, Theirs: t},
+		version:  m.ours,
+		node:     o,
+	}
+
+	// both sides changed the node itself
+	if o.Type != t.Type || (labelChanged(o, b) && labelChanged(t, b) && labelChanged(o, t)) {
+		return conflict
+	}
+	if !hasOrderedChildren(o) || !hasOrderedChildren(t) {
+		return conflict
+	}
+
+	// take the text around children from the side that changed the label of the node
+	frame, other := m.ours, m.theirs
+	fnode, onode := o, t
+	fmappings, omappings := m.ourMappings, m.theirMappings
+	if labelChanged(t, b) {
+		frame, other = other, frame
+		fnode, onode = onode, fnode
+		fmappings, omappings = omappings, fmappings
+	}
+
+	pieces := m.mergeChildren(b, frame, fnode, fmappings, other, onode, omappings)
+
+	// a conflict must occupy whole lines to be surrounded by markers
+	for _, p := range pieces {
+		if p.conflict != nil && !m.isLineAligned(p.conflict) {
+			return conflict
+		}
+	}
+
+	result := &piece{version: m.ours, node: o}
+	var text []byte
+	text = append(text, frame.Content[fnode.Pos:fnode.Children[0].Pos]...)
+	for i, p := range pieces {
+		if i > 0 {
+			text = append(text, m.separator(p, frame, fnode)...)
+		}
+		if p.conflict != nil {
+			p = m.conflictPiece(p.conflict)
+		}
+		// markers must start from a new line, conflicting code keeps the indentation
+		if bytes.HasPrefix(p.text, []byte(OursMarker)) {
+			text, _ = trimIndent(text)
+		}
+		text = append(text, p.text...)
+		result.conflicts = append(result.conflicts, p.conflicts...)
+	}
+	last := fnode.Children[len(fnode.Children)-1]
+	text = append(text, frame.Content[last.End():fnode.End()]...)
+	result.text = text
+
+	return result
+}
+
+// mergeChildren merges lists of children, the order of the frame side is preserved
+func (m *merger) mergeChildren(
+	b *gum.Tree,
+	frame *Version, fnode *gum.Tree, fmappings *mappings,
+	other *Version, onode *gum.Tree, omappings *mappings,
+) []*piece {
+	var pieces []*piece
+	// base children to their pieces in the result
+	basePieces := make(map[*gum.Tree]int)
+	inserted := make(map[*piece]bool)
+
+	for _, fc := range fnode.Children {
+		bc := childPartner(fmappings.dsts, fc, b)
+		if bc == nil {
+			// inserted by the frame side
+			p := m.nodePiece(frame, fc)
+			inserted[p] = true
+			pieces = append(pieces, p)
+			continue
+		}
+
+		oc := childPartner(omappings.srcs, bc, onode)
+		if oc == nil {
+			// deleted by the other side
+			if !fc.IsIsomorphicTo(bc) {
+				pieces = append(pieces, m.deleteConflict(bc, frame, fc))
+			}
+			continue
+		}
+
+		var p *piece
+		if frame == m.ours {
+			p = m.mergeNode(bc, fc, oc)
+		} else {
+			p = m.mergeNode(bc, oc, fc)
+		}
+		// prefer separators of the frame
+		p.version, p.node = frame, fc
+		basePieces[bc] = len(pieces)
+		pieces = append(pieces, p)
+	}
+
+	// insert changes of the other side after their preceding siblings
+	pos := 0
+	for _, oc := range onode.Children {
+		bc := childPartner(omappings.dsts, oc, b)
+		if bc != nil {
+			if i, ok := basePieces[bc]; ok {
+				pos = i + 1
+				// insertions of the frame go first
+				for pos < len(pieces) && inserted[pieces[pos]] {
+					pos++
+				}
+				continue
+			}
+			// deleted by the frame side
+			if childPartner(fmappings.srcs, bc, fnode) == nil && !oc.IsIsomorphicTo(bc) {
+				shiftPieces(basePieces, pos)
+				pieces = insertPiece(pieces, pos, m.deleteConflict(bc, other, oc))
+				pos++
+			}
+			continue
+		}
+
+		// inserted by the other side
+		shiftPieces(basePieces, pos)
+		pieces = insertPiece(pieces, pos, m.nodePiece(other, oc))
+		pos++
+	}
+
+	return pieces
+}
+
+// conflict between deletion of a subtree and its modification
+func (m *merger) deleteConflict(b *gum.Tree, v *Version, n *gum.Tree) *piece {
+	c := &Conflict{Base: b}
+	if v == m.ours {
+		c.Ours = n
+	} else {
+		c.Theirs = n
+	}
+	return &piece{conflict: c, version: v, node: n}
+}
+
+func (m *merger) nodePiece(v *Version, n *gum.Tree) *piece {
+	return &piece{text: v.Content[n.Pos:n.End()], version: v, node: n}
+}
+
+// conflictPiece surrounds both variants of the conflicting subtree with conflict markers
+func (m *merger) conflictPiece(c *Conflict) *piece {
+	var indent []byte
+	if c.Ours != nil {
+		_, indent = trimIndent(m.ours.Content[:c.Ours.Pos])
+	} else {
+		_, indent = trimIndent(m.theirs.Content[:c.Theirs.Pos])
+	}
+
+	var text []byte
+	text = append(text, OursMarker+"\n"...)
+	if c.Ours != nil {
+		text = append(text, indent...)
+		text = append(text, m.ours.Content[c.Ours.Pos:c.Ours.End()]...)
+		text = append(text, '\n')
+	}
+	text = append(text, SepMarker+"\n"...)
+	if c.Theirs != nil {
+		text = append(text, indent...)
+		text = append(text, m.theirs.Content[c.Theirs.Pos:c.Theirs.End()]...)
+		text = append(text, '\n')
+	}
+	text = append(text, TheirsMarker...)
+
+	return &piece{text: text, conflicts: []*Conflict{c}}
+}
+
+// separator returns the text that goes before the piece
+func (m *merger) separator(p *piece, frame *Version, fnode *gum.Tree) []byte {
+	n := p.node
+	parent := n.GetParent()
+	if parent != nil {
+		for i, c := range parent.Children {
+			if c == n && i > 0 {
+				return p.version.Content[parent.Children[i-1].End():n.Pos]
+			}
+		}
+	}
+
+	// the node was the first child, use any separator of the frame
+	if len(fnode.Children) > 1 {
+		return frame.Content[fnode.Children[0].End():fnode.Children[1].Pos]
+	}
+	return []byte("\n")
+}
+
+// childPartner returns the node mapped to n if it is a child of the parent
+func childPartner(m map[*gum.Tree]*gum.Tree, n, parent *gum.Tree) *gum.Tree {
+	p, ok := m[n]
+	if !ok || p.GetParent() != parent {
+		return nil
+	}
+	return p
+}
+
+// children must have positions, follow each other and be inside of the node
+func hasOrderedChildren(t *gum.Tree) bool {
+	if len(t.Children) == 0 || t.Length == 0 {
+		return false
+	}
+
+	pos := t.Pos
+	for _, c := range t.Children {
+		if c.Length == 0 || c.Pos < pos {
+			return false
+		}
+		pos = c.End()
+	}
+
+	return pos <= t.End()
+}
+
+func (m *merger) isLineAligned(c *Conflict) bool {
+	return (c.Ours == nil || isLineAligned(m.ours.Content, c.Ours)) &&
+		(c.Theirs == nil || isLineAligned(m.theirs.Content, c.Theirs))
+}
+
+// only whitespaces are allowed around the node on its lines
+func isLineAligned(content []byte, n *gum.Tree) bool {
+	for i := n.Pos - 1; i >= 0 && content[i] != '\n'; i-- {
+		if content[i] != ' ' && content[i] != '\t' {
+			return false
+		}
+	}
+	for i := n.End(); i < len(content) && content[i] != '\n'; i++ {
+		if content[i] != ' ' && content[i] != '\t' && content[i] != '\r' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trimIndent cuts whitespaces at the end of the text if they follow a new line
+func trimIndent(text []byte) ([]byte, []byte) {
+	i := len(text)
+	for i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+		i--
+	}
+	if i > 0 && text[i-1] != '\n' {
+		return text, nil
+	}
+
+	return text[:i], append([]byte(nil), text[i:]...)
+}
+
+func insertPiece(pieces []*piece, pos int, p *piece) []*piece {
+	return append(pieces[:pos], append([]*piece{p}, pieces[pos:]...)...)
+}
+
+// shift indexes of pieces to free the position
+func shiftPieces(basePieces map[*gum.Tree]int, pos int) {
+	for k, v := range basePieces {
+		if v >= pos {
+			basePieces[k] = v + 1
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package merge
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func version(t *testing.T, src string) *Version {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	require.NoError(t, err)
+	return &Version{Content: []byte(src), Tree: golang.ToTree(f)}
+}
+
+const base = `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(2)
+}
+
+func Bar() int {
+	return 1
+}
+`
+
+func TestMergeClean(t *testing.T) {
+	ours := `package foo
+
+import (
+	"fmt"
+	"os"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(10)
+	fmt.Println(2)
+	os.Exit(0)
+}
+
+func Bar() int {
+	return 1
+}
+`
+	theirs := `package foo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(strings.Repeat("2", 2))
+}
+
+func Baz() {}
+
+func Bar() int {
+	return 2
+}
+`
+	expected := `package foo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(10)
+	fmt.Println(strings.Repeat("2", 2))
+	os.Exit(0)
+}
+
+func Baz() {}
+
+func Bar() int {
+	return 2
+}
+`
+
+	res := Merge(version(t, base), version(t, ours), version(t, theirs))
+	assert.Len(t, res.Conflicts, 0)
+	assert.Equal(t, expected, string(res.Content))
+}
+
+func TestMergeConflict(t *testing.T) {
+	ours := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(3)
+}
+
+func Bar() int {
+	return 1
+}
+`
+	theirs := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(4)
+}
+`
+	expected := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+<<<<<<< ours
+	fmt.Println(3)
+=======
+	fmt.Println(4)
+>>>>>>> theirs
+}
+`
+
+	res := Merge(version(t, base), version(t, ours), version(t, theirs))
+	require.Len(t, res.Conflicts, 1)
+	c := res.Conflicts[0]
+	assert.Equal(t, "CallExpr", c.Base.Type)
+	assert.NotNil(t, c.Ours)
+	assert.NotNil(t, c.Theirs)
+	assert.Equal(t, expected, string(res.Content))
+}
+
+func TestMergeDeleteConflict(t *testing.T) {
+	ours := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(2)
+}
+
+func Bar() int {
+	return 3
+}
+`
+	theirs := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(2)
+}
+`
+	expected := `package foo
+
+import (
+	"fmt"
+)
+
+// Foo prints a number
+func Foo() {
+	fmt.Println(1)
+	fmt.Println(2)
+}
+
+<<<<<<< ours
+func Bar() int {
+	return 3
+}
+=======
+>>>>>>> theirs
+`
+
+	res := Merge(version(t, base), version(t, ours), version(t, theirs))
+	require.Len(t, res.Conflicts, 1)
+	assert.Nil(t, res.Conflicts[0].Theirs)
+	assert.Equal(t, expected, string(res.Content))
+}
+
+func TestCheckPositions(t *testing.T) {
+	assert.NoError(t, version(t, base).CheckPositions())
+
+	// positions of the tree refer to another content
+	v := version(t, base)
+	v.Content = v.Content[:len(v.Content)/2]
+	assert.Error(t, v.CheckPositions())
+
+	// parser without positions
+	assert.Error(t, (&Version{Content: []byte(base), Tree: &gum.Tree{Type: "File"}}).CheckPositions())
+}
@@ -0,0 +1,240 @@
+package gum
+
+// Port translates the actions transforming src into actions for other, a tree similar to src
+//
+// src is matched with other, nodes and parents of the actions are replaced by their partners
+// and positions are taken next to the partners of the siblings. Ported actions keep the order,
+// so Apply(other, ported) makes the same change in other.
+// Actions that can't be placed are returned as failed in their original form:
+// nodes without partners, labels and attributes changed in other differently,
+// deleted nodes with different children in other and actions depending on failed ones.
+// Updates already made in other are neither ported nor failed.
+//
+// Error is returned if the actions don't belong to src.
+func Port(actions []*Action, src, other *Tree) ([]*Action, []*Action, error) {
+	p := &porter{
+		srcToOther: make(map[*Tree]*Tree),
+		srcByID:    make(map[int]*Tree),
+		srcAp:      newApplier(src),
+		otherAp:    newApplier(other),
+	}
+	for _, m := range Match(src, other) {
+		p.srcToOther[m[0]] = m[1]
+	}
+	for _, n := range PostOrder(src) {
+		p.srcByID[n.id] = n
+	}
+
+	var ported, failed []*Action
+	for _, a := range actions {
+		pa, ok := p.port(a)
+		if ok && pa != nil {
+			if err := p.otherAp.apply(pa); err != nil {
+				ok = false
+			}
+		}
+		switch {
+		case !ok:
+			failed = append(failed, a)
+		case pa != nil:
+			ported = append(ported, pa)
+		}
+
+		// the copy of src follows the actions to find siblings for the next ones
+		if err := p.srcAp.apply(a); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return ported, failed, nil
+}
+
+// porter keeps copies of src and other transformed by the actions
+type porter struct {
+	srcToOther map[*Tree]*Tree
+	srcByID    map[int]*Tree
+	srcAp      *applier
+	otherAp    *applier
+}
+
+// port returns the action for other, nil action means there is nothing to do
+func (p *porter) port(a *Action) (*Action, bool) {
+	node, ok := p.toOther(a.Node)
+	if !ok && a.Type != Insert && a.Type != InsertTree {
+		return nil, false
+	}
+
+	switch a.Type {
+	case Insert, InsertTree, Move:
+		parent, ok := p.toOther(a.Parent)
+		if !ok {
+			return nil, false
+		}
+		srcParent, err := p.srcAp.find(a.Parent)
+		if err != nil {
+			return nil, false
+		}
+		otherParent, err := p.otherAp.find(parent)
+		if err != nil {
+			return nil, false
+		}
+
+		if a.Type != Move {
+			pos := p.position(srcParent.Children, a.Pos, otherParent)
+			return &Action{Type: a.Type, Node: a.Node, Parent: parent, Pos: pos}, true
+		}
+
+		srcNode, err := p.srcAp.find(a.Node)
+		if err != nil {
+			return nil, false
+		}
+		otherNode, err := p.otherAp.find(node)
+		if err != nil || isAncestor(otherNode, otherParent) {
+			return nil, false
+		}
+		// positions are found without the moved node
+		k := a.Pos
+		if srcNode.parent == srcParent && k > positionInParent(srcNode) {
+			k--
+		}
+		pos := p.position(without(srcParent.Children, srcNode), k, otherParent, otherNode)
+		if otherNode.parent == otherParent && pos >= positionInParent(otherNode) {
+			pos++
+		}
+		return &Action{Type: Move, Node: node, Parent: parent, Pos: pos}, true
+	}
+
+	srcNode, err := p.srcAp.find(a.Node)
+	if err != nil {
+		return nil, false
+	}
+	otherNode, err := p.otherAp.find(node)
+	if err != nil {
+		return nil, false
+	}
+
+	switch a.Type {
+	case Update:
+		if otherNode.Value == a.Value {
+			return nil, true
+		}
+		if otherNode.Value != srcNode.Value {
+			return nil, false
+		}
+		return &Action{Type: Update, Node: node, Value: a.Value}, true
+	case UpdateAttr:
+		value, _ := otherNode.GetAttr(a.Key)
+		if value == a.Value {
+			return nil, true
+		}
+		if srcValue, _ := srcNode.GetAttr(a.Key); value != srcValue {
+			return nil, false
+		}
+		return &Action{Type: UpdateAttr, Node: node, Key: a.Key, Value: a.Value}, true
+	case DeleteAttr:
+		value, ok := otherNode.GetAttr(a.Key)
+		if !ok {
+			return nil, true
+		}
+		if srcValue, _ := srcNode.GetAttr(a.Key); value != srcValue {
+			return nil, false
+		}
+		return &Action{Type: DeleteAttr, Node: node, Key: a.Key}, true
+	case Delete:
+		// children added in other would be deleted with the node
+		if len(otherNode.Children) > 0 {
+			return nil, false
+		}
+		return &Action{Type: Delete, Node: node}, true
+	case DeleteTree:
+		if !otherNode.IsIsomorphicTo(srcNode) {
+			return nil, false
+		}
+		return &Action{Type: DeleteTree, Node: node}, true
+	}
+
+	return nil, false
+}
+
+// toOther returns the partner of a src node or the inserted node if its insert was ported
+func (p *porter) toOther(n *Tree) (*Tree, bool) {
+	if _, ok := p.srcAp.inserted[n]; ok {
+		_, ok := p.otherAp.inserted[n]
+		return n, ok
+	}
+	if p.srcByID[n.id] != n {
+		return nil, false
+	}
+	o, ok := p.srcToOther[n]
+	return o, ok
+}
+
+// position finds the position in the children of the parent in other
+// equal to the position k among the siblings in src
+//
+// The position goes after the partner of the nearest sibling on the left
+// or before the partner of the nearest sibling on the right,
+// otherwise it's kept at the beginning or at the end.
+func (p *porter) position(siblings []*Tree, k int, parent *Tree, skip ...*Tree) int {
+	children := parent.Children
+	if len(skip) > 0 {
+		children = without(children, skip[0])
+	}
+	indexOf := func(c *Tree) int {
+		var n *Tree
+		if dst, ok := p.srcAp.origins[c]; ok {
+			n = dst
+		} else {
+			n = p.srcByID[c.id]
+		}
+		o, ok := p.toOther(n)
+		if !ok {
+			return -1
+		}
+		w, err := p.otherAp.find(o)
+		if err != nil {
+			return -1
+		}
+		for i, child := range children {
+			if child == w {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i := k - 1; i >= 0; i-- {
+		if j := indexOf(siblings[i]); j >= 0 {
+			return j + 1
+		}
+	}
+	for i := k; i < len(siblings); i++ {
+		if j := indexOf(siblings[i]); j >= 0 {
+			return j
+		}
+	}
+	if k >= len(siblings) || k > len(children) {
+		return len(children)
+	}
+	return k
+}
+
+func without(trees []*Tree, t *Tree) []*Tree {
+	res := make([]*Tree, 0, len(trees))
+	for _, c := range trees {
+		if c != t {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// isAncestor returns true if a is t or one of its ancestors
+func isAncestor(a, t *Tree) bool {
+	for ; t != nil; t = t.parent {
+		if t == a {
+			return true
+		}
+	}
+	return false
+}
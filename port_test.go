@@ -0,0 +1,94 @@
+package gum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPort(t *testing.T) {
+	require := require.New(t)
+
+	for _, dir := range []string{"testdata/paper", "testdata/actions"} {
+		src, dst := readFixtures(dir+"/src.json", dir+"/dst.json")
+		actions := Patch(src, dst, Match(src, dst))
+
+		// a copy of src takes all actions
+		other, _ := readFixtures(dir+"/src.json", dir+"/dst.json")
+		ported, failed, err := Port(actions, src, other)
+		require.NoError(err, dir)
+		require.Empty(failed, dir)
+		require.Len(ported, len(actions), dir)
+
+		changed, err := Apply(other, ported)
+		require.NoError(err, dir)
+		require.Equal(treeString(dst), treeString(changed), dir)
+
+		// actions don't belong to the tree
+		_, _, err = Port(actions, dst, other)
+		require.Error(err, dir)
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	require := require.New(t)
+
+	tree := func(s string) *Tree {
+		t, err := treeFromJSON(s)
+		require.NoError(err)
+		return t
+	}
+	src := tree(`{"root": {"typeLabel": "Block", "children": [
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "f", "children": []},
+			{"typeLabel": "Name", "label": "a", "children": []}
+		]},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "g", "children": []},
+			{"typeLabel": "Name", "label": "b", "children": []}
+		]}
+	]}}`)
+	dst := tree(`{"root": {"typeLabel": "Block", "children": [
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "f", "children": []},
+			{"typeLabel": "Name", "label": "x", "children": []}
+		]},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "g", "children": []},
+			{"typeLabel": "Name", "label": "y", "children": []}
+		]},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "h", "children": []},
+			{"typeLabel": "Name", "label": "c", "children": []}
+		]}
+	]}}`)
+	// other has an extra call at the beginning and a different argument of g
+	other := tree(`{"root": {"typeLabel": "Block", "children": [
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "log", "children": []},
+			{"typeLabel": "Name", "label": "msg", "children": []}
+		]},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "f", "children": []},
+			{"typeLabel": "Name", "label": "a", "children": []}
+		]},
+		{"typeLabel": "Call", "children": [
+			{"typeLabel": "Name", "label": "g", "children": []},
+			{"typeLabel": "Name", "label": "z", "children": []}
+		]}
+	]}}`)
+
+	actions := Patch(src, dst, Match(src, dst))
+	ported, failed, err := Port(actions, src, other)
+	require.NoError(err)
+
+	// update of b conflicts with z
+	require.Len(failed, 1)
+	require.Equal(Update, failed[0].Type)
+	require.Equal("b", failed[0].Node.Value)
+
+	changed, err := Apply(other, ported)
+	require.NoError(err)
+	require.Equal("(Block (Call (Name[log]) (Name[msg])) (Call (Name[f]) (Name[x])) "+
+		"(Call (Name[g]) (Name[z])) (Call (Name[h]) (Name[c])))", treeString(changed))
+}
@@ -0,0 +1,147 @@
+package gum
+
+import (
+	"sort"
+)
+
+// matchRefs corrects mappings of leaves with Ref
+//
+// entities of src and dst are linked by the majority of mappings between their uses,
+// then uses mapped to a different entity are swapped with siblings that refer to the linked one
+// and unmapped uses are mapped to unmapped siblings of the linked entity.
+func matchRefs(mappings *mappingStore) {
+	refs := linkRefs(mappings)
+	if len(refs) == 0 {
+		return
+	}
+
+	srcs := make([]*Tree, 0, len(mappings.srcs))
+	for s := range mappings.srcs {
+		srcs = append(srcs, s)
+	}
+	sort.Slice(srcs, func(i, j int) bool { return srcs[i].id < srcs[j].id })
+
+	for _, s := range srcs {
+		if !s.isLeaf() || s.Ref == "" || isRoot(s) {
+			continue
+		}
+		d := mappings.srcs[s]
+		want, ok := refs[s.Ref]
+		if !ok || d.Ref == want || isRoot(d) {
+			continue
+		}
+
+		for _, c := range d.parent.Children {
+			if c == d || !c.isLeaf() || c.Type != s.Type || c.Ref != want {
+				continue
+			}
+			other, mapped := mappings.dsts[c]
+			if mapped && refs[other.Ref] == c.Ref {
+				continue
+			}
+
+			mappings.Link(s, c)
+			if mapped {
+				mappings.Link(other, d)
+			} else {
+				delete(mappings.dsts, d)
+			}
+			break
+		}
+	}
+
+	// map uses that are left
+	for _, s := range srcs {
+		if isRoot(s) {
+			continue
+		}
+		for _, sc := range s.Children {
+			if _, ok := mappings.srcs[sc]; ok || !sc.isLeaf() || sc.Ref == "" {
+				continue
+			}
+			want, ok := refs[sc.Ref]
+			if !ok {
+				continue
+			}
+			for _, dc := range mappings.srcs[s].Children {
+				if _, ok := mappings.dsts[dc]; !ok && dc.isLeaf() && dc.Type == sc.Type && dc.Ref == want {
+					mappings.Link(sc, dc)
+					break
+				}
+			}
+		}
+	}
+}
+
+// linkRefs returns one-to-one links between src and dst entities,
+// pairs with more mappings between their uses win
+func linkRefs(mappings *mappingStore) map[string]string {
+	type refPair struct{ src, dst string }
+	votes := make(map[refPair]int)
+	for s, d := range mappings.srcs {
+		if s.Ref != "" && d.Ref != "" {
+			votes[refPair{s.Ref, d.Ref}]++
+		}
+	}
+
+	pairs := make([]refPair, 0, len(votes))
+	for p := range votes {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if votes[pairs[i]] != votes[pairs[j]] {
+			return votes[pairs[i]] > votes[pairs[j]]
+		}
+		if pairs[i].src != pairs[j].src {
+			return pairs[i].src < pairs[j].src
+		}
+		return pairs[i].dst < pairs[j].dst
+	})
+
+	refs := make(map[string]string)
+	linked := make(map[string]bool)
+	for _, p := range pairs {
+		if _, ok := refs[p.src]; ok || linked[p.dst] {
+			continue
+		}
+		refs[p.src] = p.dst
+		linked[p.dst] = true
+	}
+
+	return refs
+}
+
+// Rename is a change of the label of all uses of one entity
+type Rename struct {
+	// Ref of the entity in src
+	Ref   string
+	From  string
+	To    string
+	Nodes []*Tree
+}
+
+// Renames groups updates of nodes with the same Ref and labels into renames,
+// updates of nodes without Ref and other actions are returned as they are
+func Renames(actions []*Action) ([]*Rename, []*Action) {
+	type key struct{ ref, from, to string }
+	var renames []*Rename
+	index := make(map[key]*Rename)
+	var rest []*Action
+	for _, a := range actions {
+		if a.Type != Update || a.Node.Ref == "" {
+			rest = append(rest, a)
+			continue
+		}
+
+		k := key{a.Node.Ref, a.Node.Value, a.Value}
+		r, ok := index[k]
+		if !ok {
+			r = &Rename{Ref: k.ref, From: k.from, To: k.to}
+			index[k] = r
+			renames = append(renames, r)
+		}
+		r.Nodes = append(r.Nodes, a.Node)
+	}
+
+	return renames, rest
+}
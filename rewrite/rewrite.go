@@ -0,0 +1,749 @@
+package rewrite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+// HoleType is the type of the pattern nodes standing for any subtree.
+// The label of a hole is its name, a name ending with "..." stands for any number of trailing children.
+const HoleType = "$hole"
+
+// Example is a change given by the trees before and after it
+type Example struct {
+	Src *gum.Tree
+	Dst *gum.Tree
+	// DstSource is the source of Dst, a template learned without it can't rewrite sources
+	DstSource []byte
+}
+
+// Template is a transformation learned from examples
+//
+// Before is the pattern of the changed code and After is the code replacing it.
+// Holes of the patterns are nodes of HoleType and labels "$name",
+// a label starting with "$" itself is escaped as "$$". Holes with the same name
+// match the same content and holes of After are filled with the content matched by Before.
+type Template struct {
+	Before *gum.Tree
+	After  *gum.Tree
+	// Text is the source of After with holes as ${name} and "$" escaped as "$$"
+	Text string
+}
+
+// Learn generalizes the changes of the examples into a template
+//
+// Examples are matched by gum.Match and the smallest subtrees containing the changes are taken,
+// so an example may contain several occurrences of the change. Parts of the occurrences
+// that differ become holes: labels of nodes of the same type, trailing children of different number
+// and other subtrees. A single occurrence gives a template matching the same code only.
+// If changes are of different kinds, for example a call is replaced and an import is updated,
+// the kind made in most examples is learned.
+//
+// Error is returned if the examples have no changes or the code of After
+// differs between the examples in parts not matched by Before.
+func Learn(examples ...*Example) (*Template, error) {
+	var groups []*group
+	for i, e := range examples {
+		rs := regions(e.Src, e.Dst)
+		if len(rs) == 0 {
+			return nil, fmt.Errorf("example %d has no changes", i+1)
+		}
+		seen := make(map[*group]bool)
+		for _, r := range rs {
+			g := findGroup(groups, r)
+			if g == nil {
+				g = &group{source: e.DstSource}
+				groups = append(groups, g)
+			}
+			g.before = append(g.before, r[0])
+			g.after = append(g.after, r[1])
+			g.size += r[0].GetSize()
+			if !seen[g] {
+				seen[g] = true
+				g.examples++
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no examples")
+	}
+
+	best := groups[0]
+	for _, g := range groups[1:] {
+		if g.examples > best.examples || g.examples == best.examples && g.size > best.size {
+			best = g
+		}
+	}
+
+	gen := &generalizer{holes: make(map[string]string)}
+	before, err := gen.generalize(best.before, true)
+	if err != nil {
+		return nil, err
+	}
+	if before.Type == HoleType {
+		return nil, fmt.Errorf("changed code of the examples has nothing in common")
+	}
+	first := best.after[0]
+	if best.source != nil && first.Length > 0 {
+		gen.source = best.source
+	}
+	after, err := gen.generalize(best.after, false)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{Before: before, After: after}
+	if gen.source != nil && !gen.noText {
+		if text, ok := gen.text(first); ok {
+			t.Text = text
+		}
+	}
+	// positions belong to the examples
+	for _, p := range []*gum.Tree{before, after} {
+		p.Refresh()
+		for _, n := range gum.PreOrder(p) {
+			n.Pos, n.Length = 0, 0
+		}
+	}
+
+	return t, nil
+}
+
+// group is a kind of change, occurrences with the same types of the changed subtrees
+type group struct {
+	before   []*gum.Tree
+	after    []*gum.Tree
+	source   []byte
+	examples int
+	size     int
+}
+
+func findGroup(groups []*group, r [2]*gum.Tree) *group {
+	for _, g := range groups {
+		if sameKind([2]*gum.Tree{g.before[0], g.after[0]}, r) {
+			return g
+		}
+	}
+	return nil
+}
+
+// regions returns pairs of the smallest subtrees of src and dst containing the changes
+//
+// Changes of different children are taken separately if they are of the same kind
+// or the children belong to the roots, unless nodes are moved between the children.
+func regions(src, dst *gum.Tree) [][2]*gum.Tree {
+	partners := make(map[*gum.Tree]*gum.Tree)
+	for _, m := range gum.Match(src, dst) {
+		partners[m[0]] = m[1]
+		partners[m[1]] = m[0]
+	}
+
+	var walk func(s, d *gum.Tree) [][2]*gum.Tree
+	walk = func(s, d *gum.Tree) [][2]*gum.Tree {
+		if s.IsIsomorphicTo(d) {
+			return nil
+		}
+		whole := [][2]*gum.Tree{{s, d}}
+		if s.Type != d.Type || s.Value != d.Value || !s.EqualAttrs(d) || len(s.Children) != len(d.Children) {
+			return whole
+		}
+
+		var res [][2]*gum.Tree
+		for i := range s.Children {
+			sc, dc := s.Children[i], d.Children[i]
+			if sc.IsIsomorphicTo(dc) {
+				continue
+			}
+			if !closed(sc, dc, partners) {
+				return whole
+			}
+			// a changed leaf is taken with the parent to keep the context of the change
+			if len(sc.Children) == 0 && len(dc.Children) == 0 && s != src {
+				return whole
+			}
+			res = append(res, walk(sc, dc)...)
+		}
+		if s == src {
+			return res
+		}
+		for _, r := range res[1:] {
+			if !sameKind(r, res[0]) {
+				return whole
+			}
+		}
+		return res
+	}
+
+	return walk(src, dst)
+}
+
+func sameKind(a, b [2]*gum.Tree) bool {
+	return a[0].Type == b[0].Type && a[1].Type == b[1].Type
+}
+
+// closed returns true if partners of the nodes of the subtrees are in the other subtree
+func closed(s, d *gum.Tree, partners map[*gum.Tree]*gum.Tree) bool {
+	in := make(map[*gum.Tree]bool)
+	for _, n := range gum.PreOrder(s) {
+		in[n] = true
+	}
+	for _, n := range gum.PreOrder(d) {
+		in[n] = true
+	}
+	for n := range in {
+		if p, ok := partners[n]; ok && !in[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// generalizer anti-unifies trees of the occurrences keeping the holes for the same parts
+type generalizer struct {
+	// names of the holes by the keys of the matched parts
+	holes map[string]string
+	count int
+
+	// source of the first occurrence of After, holes are replaced in it to get the text
+	source []byte
+	spans  []span
+	noText bool
+}
+
+// span is a part of the source replaced by a hole
+type span struct {
+	pos, end int
+	hole     string
+}
+
+// generalize returns the pattern of the trees, new holes are added only if bind is true
+func (g *generalizer) generalize(ts []*gum.Tree, bind bool) (*gum.Tree, error) {
+	first := ts[0]
+	if name, ok := g.holes[subtreesKey(ts)]; ok {
+		g.replace(first.Pos, first.End(), name)
+		return &gum.Tree{Type: HoleType, Value: name}, nil
+	}
+	if same(ts) {
+		return copyPattern(first), nil
+	}
+
+	sameNode := true
+	for _, t := range ts[1:] {
+		if t.Type != first.Type || !t.EqualAttrs(first) {
+			sameNode = false
+			break
+		}
+	}
+	if !sameNode {
+		name, err := g.hole(subtreesKey(ts), bind, first)
+		if err != nil {
+			return nil, err
+		}
+		g.replace(first.Pos, first.End(), name)
+		return &gum.Tree{Type: HoleType, Value: name}, nil
+	}
+
+	n := copyNode(first)
+	n.Value = escape(first.Value)
+	labels := make([]string, len(ts))
+	for i, t := range ts {
+		labels[i] = t.Value
+	}
+	if !sameStrings(labels) {
+		name, err := g.hole(labelsKey(labels), bind, first)
+		if err != nil {
+			return nil, err
+		}
+		n.Value = "$" + name
+		// only the text of a leaf is its label
+		if g.source != nil && (len(first.Children) > 0 || string(g.source[first.Pos:first.End()]) != first.Value) {
+			g.noText = true
+		}
+		g.replace(first.Pos, first.End(), name)
+	}
+
+	count := len(first.Children)
+	for _, t := range ts[1:] {
+		if len(t.Children) != count {
+			count = -1
+			break
+		}
+	}
+	if count >= 0 {
+		for i := 0; i < count; i++ {
+			c, err := g.generalize(column(ts, i), bind)
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, c)
+		}
+		return n, nil
+	}
+
+	// common leading children are kept and the rest is a hole
+	k := 0
+	for ; hasChildren(ts, k) && same(column(ts, k)); k++ {
+		n.Children = append(n.Children, copyPattern(first.Children[k]))
+	}
+	tails := make([][]*gum.Tree, len(ts))
+	for i, t := range ts {
+		tails[i] = t.Children[k:]
+	}
+	name, err := g.hole(tailsKey(tails), bind, first)
+	if err != nil {
+		return nil, err
+	}
+	name += "..."
+	if tail := tails[0]; len(tail) > 0 {
+		g.replace(tail[0].Pos, tail[len(tail)-1].End(), name)
+	} else {
+		g.noText = true
+	}
+	n.Children = append(n.Children, &gum.Tree{Type: HoleType, Value: name})
+
+	return n, nil
+}
+
+// hole returns the name of the hole for the key, it's added if bind is true
+func (g *generalizer) hole(key string, bind bool, t *gum.Tree) (string, error) {
+	if name, ok := g.holes[key]; ok {
+		return name, nil
+	}
+	if !bind {
+		return "", fmt.Errorf("changed code %s differs between the examples and isn't matched by the pattern", t)
+	}
+	g.count++
+	name := strconv.Itoa(g.count)
+	g.holes[key] = name
+	return name, nil
+}
+
+// replace marks the part of the source of After as a hole
+func (g *generalizer) replace(pos, end int, hole string) {
+	if g.source != nil {
+		g.spans = append(g.spans, span{pos, end, hole})
+	}
+}
+
+// text returns the source of the tree with the holes, false if the holes overlap
+func (g *generalizer) text(t *gum.Tree) (string, bool) {
+	// children aren't always in the order of the source, for example sorted xml attributes
+	sort.SliceStable(g.spans, func(i, j int) bool { return g.spans[i].pos < g.spans[j].pos })
+
+	var b strings.Builder
+	last := t.Pos
+	for _, s := range g.spans {
+		if s.pos < last || s.end > t.End() {
+			return "", false
+		}
+		b.WriteString(escape(string(g.source[last:s.pos])))
+		b.WriteString("${" + s.hole + "}")
+		last = s.end
+	}
+	b.WriteString(escape(string(g.source[last:t.End()])))
+	return b.String(), true
+}
+
+func same(ts []*gum.Tree) bool {
+	for _, t := range ts[1:] {
+		if !t.IsIsomorphicTo(ts[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameStrings(ss []string) bool {
+	for _, s := range ss[1:] {
+		if s != ss[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasChildren(ts []*gum.Tree, k int) bool {
+	for _, t := range ts {
+		if k >= len(t.Children) {
+			return false
+		}
+	}
+	return true
+}
+
+func column(ts []*gum.Tree, i int) []*gum.Tree {
+	res := make([]*gum.Tree, len(ts))
+	for j, t := range ts {
+		res[j] = t.Children[i]
+	}
+	return res
+}
+
+func subtreesKey(ts []*gum.Tree) string {
+	keys := make([]string, len(ts))
+	for i, t := range ts {
+		keys[i] = hashString(t)
+	}
+	return "t:" + strings.Join(keys, ",")
+}
+
+func tailsKey(tails [][]*gum.Tree) string {
+	keys := make([]string, len(tails))
+	for i, tail := range tails {
+		hashes := make([]string, len(tail))
+		for j, t := range tail {
+			hashes[j] = hashString(t)
+		}
+		keys[i] = "(" + strings.Join(hashes, " ") + ")"
+	}
+	return "l:" + strings.Join(keys, ",")
+}
+
+func labelsKey(labels []string) string {
+	keys := make([]string, len(labels))
+	for i, l := range labels {
+		keys[i] = strconv.Quote(l)
+	}
+	return "v:" + strings.Join(keys, ",")
+}
+
+func hashString(t *gum.Tree) string {
+	h := t.GetHash()
+	return hex.EncodeToString(h[:])
+}
+
+// labelHole returns the name of the hole if the label of a pattern node is a hole
+func labelHole(label string) (string, bool) {
+	if len(label) < 2 || label[0] != '$' || label[1] == '$' {
+		return "", false
+	}
+	return label[1:], true
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func unescape(label string) string {
+	return strings.ReplaceAll(label, "$$", "$")
+}
+
+func isListHole(p *gum.Tree) bool {
+	return p.Type == HoleType && strings.HasSuffix(p.Value, "...")
+}
+
+func copyNode(t *gum.Tree) *gum.Tree {
+	return &gum.Tree{
+		Type:      t.Type,
+		Value:     t.Value,
+		Meta:      t.Meta,
+		Pos:       t.Pos,
+		Length:    t.Length,
+		Ref:       t.Ref,
+		Unordered: t.Unordered,
+		Attrs:     t.Attrs,
+	}
+}
+
+func copyTree(t *gum.Tree) *gum.Tree {
+	n := copyNode(t)
+	for _, c := range t.Children {
+		n.Children = append(n.Children, copyTree(c))
+	}
+	return n
+}
+
+// copyPattern copies a tree into a pattern without holes
+func copyPattern(t *gum.Tree) *gum.Tree {
+	n := copyNode(t)
+	n.Value = escape(t.Value)
+	for _, c := range t.Children {
+		n.Children = append(n.Children, copyPattern(c))
+	}
+	return n
+}
+
+// Match is an occurrence of the Before pattern in a tree
+type Match struct {
+	Node *gum.Tree
+	// Subtrees matched by the holes, a hole of trailing children matches any number of them
+	Subtrees map[string][]*gum.Tree
+	// Labels matched by the label holes
+	Labels map[string]string
+}
+
+// Find returns the occurrences of Before in the tree in pre-order, nested occurrences aren't reported.
+// The tree must be Refresh'ed.
+func (t *Template) Find(tree *gum.Tree) []*Match {
+	var res []*Match
+	var walk func(n *gum.Tree)
+	walk = func(n *gum.Tree) {
+		m := &Match{
+			Node:     n,
+			Subtrees: make(map[string][]*gum.Tree),
+			Labels:   make(map[string]string),
+		}
+		if m.match(t.Before, n) {
+			res = append(res, m)
+			return
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree)
+
+	return res
+}
+
+func (m *Match) match(p, t *gum.Tree) bool {
+	if p.Type == HoleType {
+		return m.bind(p.Value, []*gum.Tree{t})
+	}
+	if p.Type != t.Type || !p.EqualAttrs(t) {
+		return false
+	}
+	if name, ok := labelHole(p.Value); ok {
+		if l, ok := m.Labels[name]; ok && l != t.Value {
+			return false
+		}
+		m.Labels[name] = t.Value
+	} else if unescape(p.Value) != t.Value {
+		return false
+	}
+
+	children := p.Children
+	if k := len(children); k > 0 && isListHole(children[k-1]) {
+		if len(t.Children) < k-1 {
+			return false
+		}
+		tail := append([]*gum.Tree(nil), t.Children[k-1:]...)
+		if !m.bind(children[k-1].Value, tail) {
+			return false
+		}
+		children = children[:k-1]
+	} else if len(children) != len(t.Children) {
+		return false
+	}
+	for i, c := range children {
+		if !m.match(c, t.Children[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bind sets the subtrees of the hole, a hole matched before must match the same content
+func (m *Match) bind(name string, ts []*gum.Tree) bool {
+	bound, ok := m.Subtrees[name]
+	if !ok {
+		m.Subtrees[name] = ts
+		return true
+	}
+	if len(bound) != len(ts) {
+		return false
+	}
+	for i := range ts {
+		if !bound[i].IsIsomorphicTo(ts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// instantiate returns copies of the pattern with the holes replaced by the matched content
+func (m *Match) instantiate(p *gum.Tree) ([]*gum.Tree, error) {
+	if p.Type == HoleType {
+		ts, ok := m.Subtrees[p.Value]
+		if !ok {
+			return nil, fmt.Errorf("hole %s isn't matched by the pattern", p.Value)
+		}
+		res := make([]*gum.Tree, len(ts))
+		for i, t := range ts {
+			res[i] = copyTree(t)
+		}
+		return res, nil
+	}
+
+	n := copyNode(p)
+	n.Value = unescape(p.Value)
+	if name, ok := labelHole(p.Value); ok {
+		l, ok := m.Labels[name]
+		if !ok {
+			return nil, fmt.Errorf("label $%s isn't matched by the pattern", name)
+		}
+		n.Value = l
+	}
+	for _, c := range p.Children {
+		cs, err := m.instantiate(c)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, cs...)
+	}
+
+	return []*gum.Tree{n}, nil
+}
+
+// text returns Text of a template with the holes replaced by the matched source
+func (m *Match) text(text string, src []byte) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' {
+			b.WriteByte(text[i])
+			continue
+		}
+		if strings.HasPrefix(text[i:], "$$") {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		end := strings.IndexByte(text[i:], '}')
+		if !strings.HasPrefix(text[i:], "${") || end < 0 {
+			return "", fmt.Errorf("incorrect hole at offset %d of the template text", i)
+		}
+		name := text[i+2 : i+end]
+		if l, ok := m.Labels[name]; ok {
+			b.WriteString(l)
+		} else if ts, ok := m.Subtrees[name]; ok {
+			if len(ts) > 0 {
+				b.Write(src[ts[0].Pos:ts[len(ts)-1].End()])
+			}
+		} else {
+			return "", fmt.Errorf("hole %s isn't matched by the pattern", name)
+		}
+		i += end
+	}
+	return b.String(), nil
+}
+
+// Rewrite returns a copy of the tree with the occurrences of Before replaced by After
+// and the actions transforming the tree into the copy
+//
+// New nodes keep Meta of the examples and holes are filled with copies of the matched nodes,
+// so for a template learned from Go files golang.ApplyToSource prints the result.
+func (t *Template) Rewrite(tree *gum.Tree) (*gum.Tree, []*gum.Action, error) {
+	matches := make(map[*gum.Tree]*Match)
+	for _, m := range t.Find(tree) {
+		matches[m.Node] = m
+	}
+
+	var rewrite func(n *gum.Tree) (*gum.Tree, error)
+	rewrite = func(n *gum.Tree) (*gum.Tree, error) {
+		if m, ok := matches[n]; ok {
+			ts, err := m.instantiate(t.After)
+			if err != nil {
+				return nil, err
+			}
+			if len(ts) != 1 {
+				return nil, fmt.Errorf("after pattern must be a single tree")
+			}
+			return ts[0], nil
+		}
+		c := copyNode(n)
+		for _, child := range n.Children {
+			rc, err := rewrite(child)
+			if err != nil {
+				return nil, err
+			}
+			c.Children = append(c.Children, rc)
+		}
+		return c, nil
+	}
+
+	res, err := rewrite(tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	res.Refresh()
+
+	return res, gum.Patch(tree, res, gum.Match(tree, res)), nil
+}
+
+// RewriteSource replaces the occurrences of Before in the source of the tree by Text
+// with the holes filled by the matched source and returns the number of replaced occurrences.
+// The tree must have positions.
+func (t *Template) RewriteSource(src []byte, tree *gum.Tree) ([]byte, int, error) {
+	if t.Text == "" {
+		return nil, 0, fmt.Errorf("template has no text")
+	}
+
+	var b bytes.Buffer
+	matches := t.Find(tree)
+	last := 0
+	for _, m := range matches {
+		if m.Node.Length == 0 || m.Node.Pos < last {
+			return nil, 0, fmt.Errorf("node %s has no position in the source", m.Node)
+		}
+		text, err := m.text(t.Text, src)
+		if err != nil {
+			return nil, 0, err
+		}
+		b.Write(src[last:m.Node.Pos])
+		b.WriteString(text)
+		last = m.Node.End()
+	}
+	b.Write(src[last:])
+
+	return b.Bytes(), len(matches), nil
+}
+
+type jsonTemplate struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// ReadTemplate reads a template written by Write, nodes of the patterns have no Meta
+func ReadTemplate(r io.Reader) (*Template, error) {
+	var jt jsonTemplate
+	if err := json.NewDecoder(r).Decode(&jt); err != nil {
+		return nil, err
+	}
+	if jt.Before == nil || jt.After == nil {
+		return nil, fmt.Errorf("template must have before and after patterns")
+	}
+
+	before, err := gum.ReadTreeJSON(bytes.NewReader(jt.Before))
+	if err != nil {
+		return nil, fmt.Errorf("can't read before pattern: %s", err)
+	}
+	after, err := gum.ReadTreeJSON(bytes.NewReader(jt.After))
+	if err != nil {
+		return nil, fmt.Errorf("can't read after pattern: %s", err)
+	}
+
+	return &Template{Before: before, After: after, Text: jt.Text}, nil
+}
+
+// Write writes the template in json with the patterns in GumTree JSON format
+func (t *Template) Write(w io.Writer) error {
+	var before, after bytes.Buffer
+	if err := gum.WriteTreeJSON(&before, t.Before); err != nil {
+		return err
+	}
+	if err := gum.WriteTreeJSON(&after, t.After); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(&jsonTemplate{
+		Before: before.Bytes(),
+		After:  after.Bytes(),
+		Text:   t.Text,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,281 @@
+package rewrite
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/golang"
+	"github.com/smacker/gum/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const errorsSrc1 = `package a
+
+import (
+	"errors"
+	"fmt"
+)
+
+func check(n int) error {
+	if n < 0 {
+		return errors.New(fmt.Sprintf("negative %d", n))
+	}
+	return nil
+}
+`
+
+const errorsDst1 = `package a
+
+import (
+	"fmt"
+)
+
+func check(n int) error {
+	if n < 0 {
+		return fmt.Errorf("negative %d", n)
+	}
+	return nil
+}
+`
+
+const errorsSrc2 = `package b
+
+import (
+	"errors"
+	"fmt"
+)
+
+func open(name string, cause error) (err error) {
+	err = errors.New(fmt.Sprintf("can't open %s: %v", name, cause))
+	return
+}
+`
+
+const errorsDst2 = `package b
+
+import (
+	"errors"
+	"fmt"
+)
+
+func open(name string, cause error) (err error) {
+	err = fmt.Errorf("can't open %s: %v", name, cause)
+	return
+}
+`
+
+const target = `package c
+
+import (
+	"errors"
+	"fmt"
+)
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no args")
+	}
+	if len(args) > 2 {
+		return errors.New(fmt.Sprintf("too many args: %d", len(args)))
+	}
+	log.Println(errors.New(fmt.Sprintf("first %s", args[0])).Error())
+	return errors.New(fmt.Sprintf("done"))
+}
+`
+
+const targetRewritten = `package c
+
+import (
+	"errors"
+	"fmt"
+)
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no args")
+	}
+	if len(args) > 2 {
+		return fmt.Errorf("too many args: %d", len(args))
+	}
+	log.Println(fmt.Errorf("first %s", args[0]).Error())
+	return fmt.Errorf("done")
+}
+`
+
+func parse(t *testing.T, src string) *gum.Tree {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	require.NoError(t, err)
+	return golang.ToTree(f)
+}
+
+func example(t *testing.T, src, dst string) *Example {
+	return &Example{Src: parse(t, src), Dst: parse(t, dst), DstSource: []byte(dst)}
+}
+
+func learnErrors(t *testing.T) *Template {
+	tmpl, err := Learn(
+		example(t, errorsSrc1, errorsDst1),
+		example(t, errorsSrc2, errorsDst2),
+	)
+	require.NoError(t, err)
+	return tmpl
+}
+
+func TestLearn(t *testing.T) {
+	assert := assert.New(t)
+	tmpl := learnErrors(t)
+
+	// the change of imports is made only in one example
+	assert.Equal("CallExpr", tmpl.Before.Type)
+	require.Len(t, tmpl.Before.Children, 2)
+	assert.Equal("New", tmpl.Before.Children[0].Children[1].Value)
+	sprintf := tmpl.Before.Children[1]
+	require.Len(t, sprintf.Children, 2)
+	assert.Equal("Sprintf", sprintf.Children[0].Children[1].Value)
+	// arguments of different number are a hole
+	assert.Equal(HoleType, sprintf.Children[1].Type)
+	assert.Equal("1...", sprintf.Children[1].Value)
+
+	assert.Equal("CallExpr", tmpl.After.Type)
+	require.Len(t, tmpl.After.Children, 2)
+	assert.Equal("Errorf", tmpl.After.Children[0].Children[1].Value)
+	assert.Equal(HoleType, tmpl.After.Children[1].Type)
+	assert.Equal("1...", tmpl.After.Children[1].Value)
+
+	assert.Equal("fmt.Errorf(${1...})", tmpl.Text)
+}
+
+func TestLearnLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpl, err := Learn(
+		example(t, "package a\n\nfunc f() {\n\ti = i + 1\n}\n", "package a\n\nfunc f() {\n\ti++\n}\n"),
+		example(t, "package a\n\nfunc f() {\n\tn = n + 1\n}\n", "package a\n\nfunc f() {\n\tn++\n}\n"),
+	)
+	require.NoError(t, err)
+	assert.Equal("${1}++", tmpl.Text)
+
+	src := "package a\n\nfunc g() {\n\tx = x + 1\n\ty = x + 1\n\tz = z + 2\n}\n"
+	res, n, err := tmpl.RewriteSource([]byte(src), parse(t, src))
+	require.NoError(t, err)
+	assert.Equal(1, n)
+	// the same label matches the same name only
+	assert.Equal("package a\n\nfunc g() {\n\tx++\n\ty = x + 1\n\tz = z + 2\n}\n", string(res))
+}
+
+func TestLearnSingleExample(t *testing.T) {
+	tmpl, err := Learn(example(t, errorsSrc1, errorsDst1))
+	require.NoError(t, err)
+	assert.Equal(t, `fmt.Errorf("negative %d", n)`, tmpl.Text)
+
+	src := "package a\n\nfunc f() {\n\tg(errors.New(fmt.Sprintf(\"negative %d\", n)))\n\tg(errors.New(fmt.Sprintf(\"negative %d\", m)))\n}\n"
+	_, n, err := tmpl.RewriteSource([]byte(src), parse(t, src))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestLearnErrors(t *testing.T) {
+	_, err := Learn(example(t, errorsSrc1, errorsSrc1))
+	assert.EqualError(t, err, "example 1 has no changes")
+
+	// the new literal differs and can't be taken from the pattern
+	_, err = Learn(
+		example(t, "package a\n\nvar x = f(a)\n", "package a\n\nvar x = g(1)\n"),
+		example(t, "package a\n\nvar x = f(b)\n", "package a\n\nvar x = g(2)\n"),
+	)
+	assert.Error(t, err)
+
+	_, err = Learn(
+		example(t, "package a\n\nvar x = a + b\n", "package a\n\nvar x = f(a, b)\n"),
+		example(t, "package a\n\nvar x = a * b\n", "package a\n\nvar x = f(a, b)\n"),
+	)
+	assert.EqualError(t, err, "changed code of the examples has nothing in common")
+}
+
+func TestLearnXMLAttributes(t *testing.T) {
+	xmlExample := func(src, dst string) *Example {
+		s, err := xml.Parse([]byte(src))
+		require.NoError(t, err)
+		d, err := xml.Parse([]byte(dst))
+		require.NoError(t, err)
+		return &Example{Src: s, Dst: d, DstSource: []byte(dst)}
+	}
+
+	// attributes are sorted by the name, holes of the text are in the order of the source
+	tmpl, err := Learn(
+		xmlExample(`<p><img src="a.png" alt="A"/></p>`, `<p><img src="a.png" alt="A" loading="lazy"/></p>`),
+		xmlExample(`<p><img src="b.png" alt="B"/></p>`, `<p><img src="b.png" alt="B" loading="lazy"/></p>`),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `<img src="${2}" alt="${1}" loading="lazy"/>`, tmpl.Text)
+
+	src := `<div><img src="c.png" alt="C"/></div>`
+	tree, err := xml.Parse([]byte(src))
+	require.NoError(t, err)
+	res, n, err := tmpl.RewriteSource([]byte(src), tree)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, `<div><img src="c.png" alt="C" loading="lazy"/></div>`, string(res))
+}
+
+func TestFind(t *testing.T) {
+	tmpl := learnErrors(t)
+
+	matches := tmpl.Find(parse(t, target))
+	require.Len(t, matches, 3)
+	m := matches[0]
+	assert.Equal(t, "CallExpr", m.Node.Type)
+	args := m.Subtrees["1..."]
+	require.Len(t, args, 2)
+	assert.Equal(t, `"too many args: %d"`, args[0].Value)
+	assert.Len(t, matches[2].Subtrees["1..."], 1)
+}
+
+func TestRewriteSource(t *testing.T) {
+	tmpl := learnErrors(t)
+
+	res, n, err := tmpl.RewriteSource([]byte(target), parse(t, target))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, targetRewritten, string(res))
+
+	_, _, err = (&Template{Before: tmpl.Before, After: tmpl.After}).RewriteSource([]byte(target), parse(t, target))
+	assert.EqualError(t, err, "template has no text")
+}
+
+func TestRewrite(t *testing.T) {
+	tmpl := learnErrors(t)
+	tree := parse(t, target)
+
+	res, actions, err := tmpl.Rewrite(tree)
+	require.NoError(t, err)
+	assert.True(t, res.IsIsomorphicTo(parse(t, targetRewritten)))
+
+	// new nodes keep ast nodes of the examples
+	b, err := golang.ApplyToSource([]byte(target), actions)
+	require.NoError(t, err)
+	assert.Equal(t, targetRewritten, string(b))
+}
+
+func TestTemplateJSON(t *testing.T) {
+	tmpl := learnErrors(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+	read, err := ReadTemplate(&buf)
+	require.NoError(t, err)
+	assert.True(t, read.Before.IsIsomorphicTo(tmpl.Before))
+	assert.True(t, read.After.IsIsomorphicTo(tmpl.After))
+	assert.Equal(t, tmpl.Text, read.Text)
+
+	res, _, err := read.RewriteSource([]byte(target), parse(t, target))
+	require.NoError(t, err)
+	assert.Equal(t, targetRewritten, string(res))
+
+	_, err = ReadTemplate(bytes.NewBufferString(`{"text": "x"}`))
+	assert.Error(t, err)
+}
@@ -133,12 +133,8 @@ func (m *subtreeMatcher) filterMappings(mm *multiMapping, maxTreeSize int) {
 }
 
 func (m *subtreeMatcher) addMappingRecursively(src, dst *Tree) {
-	srcTrees := getTrees(src)
-	dstTrees := getTrees(dst)
-	for i := 0; i < len(srcTrees); i++ {
-		currentSrcTree := srcTrees[i]
-		currentDstTree := dstTrees[i]
-		m.addMapping(currentSrcTree, currentDstTree)
+	for _, p := range isomorphicPairs(src, dst) {
+		m.addMapping(p[0], p[1])
 	}
 }
 
@@ -2,8 +2,9 @@ package gum
 
 import (
 	"crypto/md5"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 )
 
 // Tree is an internal representation of AST tree
@@ -12,6 +13,21 @@ type Tree struct {
 	Value    string  `json:"label"`
 	Children []*Tree `json:"children"`
 	Meta     interface{}
+	// Pos is the offset of the node in the source in bytes
+	Pos int `json:"-"`
+	// Length is the size of the node in the source in bytes.
+	// Zero length means the parser doesn't provide positions.
+	Length int `json:"-"`
+	// Ref identifies the entity the node refers to, for example a variable.
+	// Nodes with the same Ref are uses of the same entity within the tree,
+	// the matcher prefers to map them to uses of the same entity in the other tree.
+	Ref string `json:"-"`
+	// Unordered marks children of the node as a set, for example members of an object.
+	// The matcher ignores their positions and reordering of them isn't a change.
+	Unordered bool `json:"-"`
+	// Attrs are properties of the node that aren't nodes themselves, for example an operator.
+	// They are a part of the content of the node and changes of them are reported by UpdateAttr actions.
+	Attrs []Attr `json:"-"`
 
 	id     int
 	parent *Tree
@@ -20,6 +36,47 @@ type Tree struct {
 	hash   [16]byte
 }
 
+// Attr is a named property of a node
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// GetAttr returns the value of the attribute and whether the node has it
+func (t *Tree) GetAttr(key string) (string, bool) {
+	for _, a := range t.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// SetAttr sets the value of the attribute keeping the order of existing ones,
+// new attributes are added to the end
+func (t *Tree) SetAttr(key, value string) {
+	for i, a := range t.Attrs {
+		if a.Key == key {
+			t.Attrs[i].Value = value
+			return
+		}
+	}
+	t.Attrs = append(t.Attrs, Attr{Key: key, Value: value})
+}
+
+// EqualAttrs returns true if both nodes have the same attributes in the same order
+func (t *Tree) EqualAttrs(o *Tree) bool {
+	if len(t.Attrs) != len(o.Attrs) {
+		return false
+	}
+	for i, a := range t.Attrs {
+		if o.Attrs[i] != a {
+			return false
+		}
+	}
+	return true
+}
+
 func (t *Tree) String() string {
 	return fmt.Sprintf("%s%s%s", t.Type, "@@", t.Value)
 }
@@ -43,6 +100,27 @@ func (t *Tree) GetParent() *Tree {
 	return t.parent
 }
 
+// GetSize returns number of nodes in the tree. Available only after Refresh.
+func (t *Tree) GetSize() int {
+	return t.size
+}
+
+// GetHeight returns height of the tree, 1 for a leaf. Available only after Refresh.
+func (t *Tree) GetHeight() int {
+	return t.height
+}
+
+// GetHash returns hash of the content of the tree.
+// Isomorphic trees have equal hashes. Available only after Refresh.
+func (t *Tree) GetHash() [16]byte {
+	return t.hash
+}
+
+// End returns the offset of the first byte after the node in the source
+func (t *Tree) End() int {
+	return t.Pos + t.Length
+}
+
 // IsIsomorphicTo returns true if the content of the trees is considered equal.
 // Both trees must be Refresh'ed.
 func (t *Tree) IsIsomorphicTo(o *Tree) bool {
@@ -54,11 +132,27 @@ func (t *Tree) IsIsomorphicTo(o *Tree) bool {
 }
 
 func (t *Tree) staticHashString() string {
-	result := "[(" + t.String()
-	for _, child := range t.Children {
-		result += child.staticHashString()
+	children := make([]string, len(t.Children))
+	for i, child := range t.Children {
+		children[i] = child.staticHashString()
 	}
-	return result + ")]"
+	// sets with the same children in different order are isomorphic
+	if t.Unordered {
+		sort.Strings(children)
+	}
+	return "[(" + t.String() + t.attrsString() + strings.Join(children, "") + ")]"
+}
+
+// attrsString is empty for nodes without attributes to keep hashes of such nodes unchanged
+func (t *Tree) attrsString() string {
+	if len(t.Attrs) == 0 {
+		return ""
+	}
+	attrs := make([]string, len(t.Attrs))
+	for i, a := range t.Attrs {
+		attrs[i] = a.Key + "=" + a.Value
+	}
+	return "{" + strings.Join(attrs, ",") + "}"
 }
 
 func (t *Tree) isLeaf() bool {
@@ -143,15 +237,7 @@ func (t *Tree) refreshHash() {
 }
 
 func treeFromJSON(s string) (*Tree, error) {
-	var root struct {
-		T Tree `json:"root"`
-	}
-	if err := json.Unmarshal([]byte(s), &root); err != nil {
-		return nil, err
-	}
-	root.T.Refresh()
-
-	return &root.T, nil
+	return ReadTreeJSON(strings.NewReader(s))
 }
 
 func isRoot(t *Tree) bool {
@@ -216,6 +302,48 @@ func putTrees(trees map[int]*Tree, t *Tree) {
 	}
 }
 
+// isomorphicPairs pairs nodes of isomorphic trees,
+// children of unordered nodes are paired by content instead of position
+func isomorphicPairs(src, dst *Tree) []Mapping {
+	pairs := []Mapping{{src, dst}}
+	if !src.Unordered && !dst.Unordered {
+		for i, c := range src.Children {
+			pairs = append(pairs, isomorphicPairs(c, dst.Children[i])...)
+		}
+		return pairs
+	}
+
+	paired := make([]bool, len(dst.Children))
+	for _, c := range src.Children {
+		for i, d := range dst.Children {
+			if !paired[i] && c.hash == d.hash {
+				paired[i] = true
+				pairs = append(pairs, isomorphicPairs(c, d)...)
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// MarkUnordered marks nodes of the types as having unordered children,
+// the tree must be refreshed after that
+func MarkUnordered(t *Tree, types ...string) {
+	for _, typ := range types {
+		if t.Type == typ {
+			t.Unordered = true
+		}
+	}
+	for _, c := range t.Children {
+		MarkUnordered(c, types...)
+	}
+}
+
+// hasUnorderedChildren returns true if the children of the nodes of both trees are sets
+func hasUnorderedChildren(src, dst *Tree) bool {
+	return src != nil && dst != nil && src.Unordered && dst.Unordered
+}
+
 func getChildPosition(t *Tree, child *Tree) int {
 	idx := -1
 	for i, c := range t.Children {
@@ -0,0 +1,65 @@
+package tsitter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/gum"
+)
+
+// ChangedRanges returns ranges of the new tree that differ from the old tree.
+// The old tree must be edited with sitter.Tree.Edit before it's used to parse the new one.
+//
+// go-tree-sitter doesn't expose ts_tree_get_changed_ranges,
+// the function follows the same idea: nodes reused by the parser are skipped
+// and only ranges of nodes with changed structure or content are returned.
+func ChangedRanges(oldTree, newTree *sitter.Tree) []gum.Range {
+	var ranges []gum.Range
+	changedRanges(oldTree.RootNode(), newTree.RootNode(), &ranges)
+	return ranges
+}
+
+func changedRanges(old, n *sitter.Node, ranges *[]gum.Range) {
+	if isReused(old, n) {
+		return
+	}
+	if old.Symbol() != n.Symbol() || n.ChildCount() == 0 || old.ChildCount() == 0 {
+		*ranges = append(*ranges, gum.Range{Start: int(n.StartByte()), End: int(n.EndByte())})
+		return
+	}
+
+	oldCount, count := int(old.ChildCount()), int(n.ChildCount())
+	start := 0
+	for start < oldCount && start < count && isReused(old.Child(start), n.Child(start)) {
+		start++
+	}
+	oldEnd, end := oldCount, count
+	for oldEnd > start && end > start && isReused(old.Child(oldEnd-1), n.Child(end-1)) {
+		oldEnd--
+		end--
+	}
+
+	if oldEnd-start == end-start {
+		for i := start; i < end; i++ {
+			changedRanges(old.Child(i), n.Child(i), ranges)
+		}
+		return
+	}
+
+	r := gum.Range{Start: int(n.StartByte()), End: int(n.StartByte())}
+	if start > 0 {
+		r.Start = int(n.Child(start - 1).EndByte())
+		r.End = r.Start
+	}
+	if end > start {
+		r.Start = int(n.Child(start).StartByte())
+		r.End = int(n.Child(end - 1).EndByte())
+	}
+	*ranges = append(*ranges, r)
+}
+
+// isReused returns true if the node wasn't affected by edits
+func isReused(old, n *sitter.Node) bool {
+	return !old.HasChanges() &&
+		old.Symbol() == n.Symbol() &&
+		old.StartByte() == n.StartByte() &&
+		old.EndByte() == n.EndByte()
+}
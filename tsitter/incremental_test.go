@@ -0,0 +1,82 @@
+package tsitter
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalUpdate(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/sample.py")
+	require.NoError(t, err)
+	lang := GetLanguage("python")
+
+	parser := sitter.NewParser()
+	parser.SetLanguage(lang.Grammar())
+	prevTree, err := parser.ParseCtx(context.Background(), nil, b)
+	require.NoError(t, err)
+
+	src := lang.ToTree(prevTree.RootNode(), b)
+	prevDst := lang.ToTree(prevTree.RootNode(), b)
+	require.Empty(t, gum.Patch(src, prevDst, gum.Match(src, prevDst)))
+
+	cases := []struct {
+		old, new string
+		actions  []gum.Operation
+	}{
+		{"ratio=0.5", "ratio=0.25", []gum.Operation{gum.Update}},
+		{"        return lambda", "        print()\n        return lambda", []gum.Operation{gum.InsertTree}},
+		{", os.sep", "", []gum.Operation{gum.DeleteTree}},
+	}
+	for _, c := range cases {
+		t.Run(c.old, func(t *testing.T) {
+			content, edit := editSource(b, c.old, c.new)
+			oldTree := prevTree.Copy()
+			oldTree.Edit(edit)
+			newTree, err := parser.ParseCtx(context.Background(), oldTree, content)
+			require.NoError(t, err)
+
+			ranges := ChangedRanges(oldTree, newTree)
+			require.NotEmpty(t, ranges)
+			for _, r := range ranges {
+				assert.True(t, r.Start <= int(edit.NewEndIndex) && int(edit.StartIndex) <= r.End,
+					"range %v is far from the edit", r)
+			}
+
+			dst := lang.ToTree(newTree.RootNode(), content)
+			inc := gum.NewIncremental(src, prevDst)
+			inc.Update(dst, ranges)
+			var ops []gum.Operation
+			for _, a := range gum.Patch(src, dst, inc.Mappings()) {
+				ops = append(ops, a.Type)
+			}
+			assert.Equal(t, c.actions, ops)
+		})
+	}
+}
+
+func editSource(b []byte, old, new string) ([]byte, sitter.EditInput) {
+	start := bytes.Index(b, []byte(old))
+	content := append(append(append([]byte{}, b[:start]...), new...), b[start+len(old):]...)
+
+	point := func(b []byte, offset int) sitter.Point {
+		row := bytes.Count(b[:offset], []byte("\n"))
+		col := offset - (bytes.LastIndexByte(b[:offset], '\n') + 1)
+		return sitter.Point{Row: uint32(row), Column: uint32(col)}
+	}
+
+	return content, sitter.EditInput{
+		StartIndex:  uint32(start),
+		OldEndIndex: uint32(start + len(old)),
+		NewEndIndex: uint32(start + len(new)),
+		StartPoint:  point(b, start),
+		OldEndPoint: point(b, start+len(old)),
+		NewEndPoint: point(content, start+len(new)),
+	}
+}
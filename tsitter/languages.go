@@ -0,0 +1,212 @@
+package tsitter
+
+import (
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// Language is a tree-sitter grammar with types of nodes that carry labels
+type Language struct {
+	Name       string
+	Extensions []string
+	// TokenTypes are types of nodes which source text is used as the label.
+	// With automatic labels true forces the label and false disables it.
+	TokenTypes map[string]bool
+
+	grammar    func() *sitter.Language
+	autoLabels bool
+}
+
+// WithAutoLabels returns a copy of the language that takes labels from named nodes
+// without named children and operators of expressions.
+// Overrides are added to TokenTypes to force labels of the types on or off.
+func (l *Language) WithAutoLabels(overrides map[string]bool) *Language {
+	cp := *l
+	cp.autoLabels = true
+	cp.TokenTypes = make(map[string]bool, len(l.TokenTypes)+len(overrides))
+	for t, v := range l.TokenTypes {
+		cp.TokenTypes[t] = v
+	}
+	for t, v := range overrides {
+		cp.TokenTypes[t] = v
+	}
+
+	return &cp
+}
+
+// Symbols returns types of named nodes of the grammar
+func (l *Language) Symbols() []string {
+	g := l.Grammar()
+	var symbols []string
+	seen := make(map[string]bool)
+	for i := uint32(0); i < g.SymbolCount(); i++ {
+		if g.SymbolType(sitter.Symbol(i)) != sitter.SymbolTypeRegular {
+			continue
+		}
+		name := g.SymbolName(sitter.Symbol(i))
+		if !seen[name] {
+			seen[name] = true
+			symbols = append(symbols, name)
+		}
+	}
+
+	return symbols
+}
+
+// Grammar returns tree-sitter language to parse the sources
+func (l *Language) Grammar() *sitter.Language {
+	return l.grammar()
+}
+
+func tokenTypes(types ...string) map[string]bool {
+	m := make(map[string]bool, len(types))
+	for _, t := range types {
+		m[t] = true
+	}
+	return m
+}
+
+var languages = []*Language{
+	{
+		Name:       "go",
+		Extensions: []string{".go"},
+		TokenTypes: goTokenTypes,
+		grammar:    golang.GetLanguage,
+	},
+	{
+		Name:       "python",
+		Extensions: []string{".py"},
+		TokenTypes: tokenTypes(
+			"identifier", "integer", "float", "true", "false", "none",
+			"comment", "string_content", "escape_sequence",
+		),
+		grammar: python.GetLanguage,
+	},
+	{
+		Name:       "javascript",
+		Extensions: []string{".js", ".mjs", ".cjs", ".jsx"},
+		TokenTypes: tokenTypes(
+			"identifier", "property_identifier", "shorthand_property_identifier",
+			"statement_identifier", "private_property_identifier",
+			"number", "true", "false", "null", "undefined", "this", "super",
+			"comment", "string_fragment", "escape_sequence", "regex_pattern", "regex_flags",
+		),
+		grammar: javascript.GetLanguage,
+	},
+	{
+		Name:       "typescript",
+		Extensions: []string{".ts", ".mts", ".cts"},
+		TokenTypes: typescriptTokenTypes,
+		grammar:    typescript.GetLanguage,
+	},
+	{
+		Name:       "tsx",
+		Extensions: []string{".tsx"},
+		TokenTypes: typescriptTokenTypes,
+		grammar:    tsx.GetLanguage,
+	},
+	{
+		Name:       "java",
+		Extensions: []string{".java"},
+		TokenTypes: tokenTypes(
+			"identifier", "type_identifier", "modifiers",
+			"decimal_integer_literal", "hex_integer_literal", "octal_integer_literal", "binary_integer_literal",
+			"decimal_floating_point_literal", "hex_floating_point_literal",
+			"character_literal", "string_fragment", "escape_sequence",
+			"true", "false", "null_literal", "this", "super",
+			"integral_type", "floating_point_type", "boolean_type", "void_type",
+			"line_comment", "block_comment",
+		),
+		grammar: java.GetLanguage,
+	},
+	{
+		Name:       "rust",
+		Extensions: []string{".rs"},
+		TokenTypes: tokenTypes(
+			"identifier", "field_identifier", "type_identifier", "primitive_type", "shorthand_field_identifier",
+			"integer_literal", "float_literal", "boolean_literal", "char_literal",
+			"string_content", "escape_sequence", "self", "crate", "super", "metavariable",
+			"visibility_modifier", "mutable_specifier", "line_comment", "block_comment", "doc_comment",
+		),
+		grammar: rust.GetLanguage,
+	},
+	{
+		Name:       "c",
+		Extensions: []string{".c", ".h"},
+		TokenTypes: tokenTypes(
+			"identifier", "field_identifier", "type_identifier", "statement_identifier", "primitive_type",
+			"number_literal", "char_literal", "character", "string_content", "escape_sequence", "system_lib_string",
+			"true", "false", "null", "storage_class_specifier", "type_qualifier", "preproc_arg", "comment",
+		),
+		grammar: c.GetLanguage,
+	},
+	{
+		Name:       "cpp",
+		Extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"},
+		TokenTypes: tokenTypes(
+			"identifier", "field_identifier", "type_identifier", "namespace_identifier", "statement_identifier",
+			"primitive_type", "auto", "number_literal", "char_literal", "character", "string_content",
+			"raw_string_content", "escape_sequence", "system_lib_string", "true", "false", "null", "this",
+			"access_specifier", "storage_class_specifier", "type_qualifier", "virtual",
+			"explicit_function_specifier", "operator_name", "preproc_arg", "comment",
+		),
+		grammar: cpp.GetLanguage,
+	},
+	{
+		Name:       "ruby",
+		Extensions: []string{".rb"},
+		TokenTypes: tokenTypes(
+			"identifier", "constant", "instance_variable", "class_variable", "global_variable",
+			"simple_symbol", "hash_key_symbol", "integer", "float", "true", "false", "nil", "self", "super",
+			"string_content", "escape_sequence", "comment",
+		),
+		grammar: ruby.GetLanguage,
+	},
+}
+
+var typescriptTokenTypes = tokenTypes(
+	"identifier", "property_identifier", "shorthand_property_identifier", "type_identifier",
+	"statement_identifier", "private_property_identifier", "predefined_type", "accessibility_modifier",
+	"number", "true", "false", "null", "undefined", "this", "super",
+	"comment", "string_fragment", "escape_sequence", "regex_pattern", "regex_flags",
+)
+
+// Languages returns all supported languages
+func Languages() []*Language {
+	return languages
+}
+
+// GetLanguage returns the language by name or nil if it isn't supported
+func GetLanguage(name string) *Language {
+	for _, l := range languages {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// DetectLanguage returns the language of the file by extension or nil if it isn't supported
+func DetectLanguage(path string) *Language {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, l := range languages {
+		for _, e := range l.Extensions {
+			if e == ext {
+				return l
+			}
+		}
+	}
+	return nil
+}
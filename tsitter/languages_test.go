@@ -0,0 +1,155 @@
+package tsitter
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguages(t *testing.T) {
+	cases := []struct {
+		file     string
+		language string
+		labels   []string
+	}{
+		{"sample.py", "python", []string{"Greeter", "hello ", "0.5", "None"}},
+		{"sample.js", "javascript", []string{"Greeter", "readFile", "hello ", "undefined"}},
+		{"sample.ts", "typescript", []string{"Greeter", "number", "public", "hello "}},
+		{"sample.java", "java", []string{"Greeter", "0.5", "'c'", "hello "}},
+		{"sample.rs", "rust", []string{"Greeter", "f64", "'c'", "hello {}"}},
+		{"sample.c", "c", []string{"greeter", "<stdio.h>", "printf", "hello %s"}},
+		{"sample.cpp", "cpp", []string{"Greeter", "std", "nullptr", "hello "}},
+		{"sample.rb", "ruby", []string{"Greeter", "@name", ":name", "hello "}},
+		{"src.go", "go", []string{"main", "Println"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.language, func(t *testing.T) {
+			lang := DetectLanguage("testdata/" + c.file)
+			require.NotNil(t, lang)
+			assert.Equal(t, c.language, lang.Name)
+
+			b, err := ioutil.ReadFile("testdata/" + c.file)
+			require.NoError(t, err)
+			tree, err := Parse(b, lang)
+			require.NoError(t, err)
+
+			labels := make(map[string]bool)
+			for _, n := range gum.PreOrder(tree) {
+				assert.NotEqual(t, "ERROR", n.Type)
+				if n.Value != "" {
+					labels[n.Value] = true
+					assert.Equal(t, n.Value, string(b[n.Pos:n.End()]))
+				}
+			}
+			for _, l := range c.labels {
+				assert.True(t, labels[l], "label %q not found", l)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	assert.Equal(t, "tsx", DetectLanguage("component.TSX").Name)
+	assert.Equal(t, "c", DetectLanguage("/path/to/header.h").Name)
+	assert.Nil(t, DetectLanguage("README"))
+	assert.Nil(t, GetLanguage("cobol"))
+	assert.Equal(t, "rust", GetLanguage("rust").Name)
+}
+
+func TestLanguageDiff(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/sample.py")
+	require.NoError(t, err)
+	lang := GetLanguage("python")
+
+	src, err := Parse(b, lang)
+	require.NoError(t, err)
+	dst, err := Parse([]byte(strings.Replace(string(b), "ratio=0.5", "ratio=0.25", 1)), lang)
+	require.NoError(t, err)
+
+	actions := gum.Patch(src, dst, gum.Match(src, dst))
+	require.Len(t, actions, 1)
+	assert.Equal(t, gum.Update, actions[0].Type)
+	assert.Equal(t, "0.25", actions[0].Value)
+}
+
+func TestAutoLabels(t *testing.T) {
+	cases := []struct {
+		language  string
+		source    string
+		overrides map[string]bool
+		labels    map[string]string
+	}{
+		{
+			language: "go",
+			source:   "package p\n\n// c\nvar x = a + 1.5\n",
+			labels: map[string]string{
+				"identifier":        "x",
+				"float_literal":     "1.5",
+				"binary_expression": "+",
+				"comment":           "// c",
+			},
+		},
+		{
+			language:  "python",
+			source:    "# c\nx = not a or b\n",
+			overrides: map[string]bool{"comment": false},
+			labels: map[string]string{
+				"identifier":           "x",
+				"not_operator":         "",
+				"boolean_operator":     "or",
+				"comment":              "",
+				"expression_statement": "",
+			},
+		},
+		{
+			language:  "javascript",
+			source:    "let s = 'str' + -1;\n",
+			overrides: map[string]bool{"string": true},
+			labels: map[string]string{
+				"identifier":        "s",
+				"string":            "'str'",
+				"unary_expression":  "-",
+				"binary_expression": "+",
+				"number":            "1",
+			},
+		},
+		{
+			language: "rust",
+			source:   "fn f() { let v = x * 2; }\n",
+			labels: map[string]string{
+				"identifier":        "f",
+				"integer_literal":   "2",
+				"binary_expression": "*",
+				"function_item":     "",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.language, func(t *testing.T) {
+			lang := GetLanguage(c.language).WithAutoLabels(c.overrides)
+			tree, err := Parse([]byte(c.source), lang)
+			require.NoError(t, err)
+
+			labels := make(map[string]string)
+			for _, n := range gum.PreOrder(tree) {
+				if _, ok := labels[n.Type]; !ok {
+					labels[n.Type] = n.Value
+				}
+			}
+			for typ, label := range c.labels {
+				require.Contains(t, labels, typ)
+				assert.Equal(t, label, labels[typ], typ)
+			}
+		})
+	}
+
+	// the original language isn't modified
+	assert.False(t, GetLanguage("javascript").TokenTypes["string"])
+	assert.Contains(t, GetLanguage("go").Symbols(), "binary_expression")
+}
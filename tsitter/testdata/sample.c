@@ -0,0 +1,16 @@
+#include <stdio.h>
+#define COUNT 1
+
+/* greets people */
+struct greeter {
+    const char *name;
+    int count;
+};
+
+static int greet(struct greeter *g, double ratio) {
+    char c = 'c';
+    if (g->count > 0 && !0) {
+        printf("hello %s\n", g->name);
+    }
+    return 1;
+}
@@ -0,0 +1,24 @@
+#include <iostream>
+
+// greets people
+namespace example {
+
+template <typename T>
+class Greeter {
+public:
+    explicit Greeter(std::string name) : name_(name) {}
+
+    bool greet(T value, double ratio = 0.5) const {
+        auto c = 'c';
+        if (count_ > 0 && !false && this != nullptr) {
+            std::cout << "hello " << name_ << std::endl;
+        }
+        return true;
+    }
+
+private:
+    std::string name_;
+    int count_ = 1;
+};
+
+}
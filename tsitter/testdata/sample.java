@@ -0,0 +1,19 @@
+package com.example;
+
+import java.util.List;
+
+// greets people
+public class Greeter<T> {
+    private String name = "world";
+    private int count = 1;
+    private double ratio = 0.5;
+    private char c = 'c';
+
+    /** Greets */
+    public boolean greet(List<T> items, int... rest) {
+        if (this.name != null && !false) {
+            System.out.println("hello " + name);
+        }
+        return true;
+    }
+}
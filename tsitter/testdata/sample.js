@@ -0,0 +1,19 @@
+import { readFile } from "fs";
+
+// greets people
+class Greeter {
+  constructor(name = "world") {
+    this.name = name;
+    this.items = [1, 2.5, true, null, undefined];
+  }
+
+  greet(...args) {
+    const re = /hello/g;
+    if (this.name !== "" && !false) {
+      console.log(`hello ${this.name}`, re);
+    }
+    return (x) => x + 1;
+  }
+}
+
+export default Greeter;
@@ -0,0 +1,16 @@
+import os
+from typing import List
+
+
+class Greeter(object):
+    """Greets people"""
+
+    def __init__(self, name: str = "world", count=1, ratio=0.5):
+        self.name = name
+        self.items: List[int] = [1, 2, 3]
+
+    def greet(self, *args, **kwargs):
+        # say hello
+        if self.name is not None and True:
+            print(f"hello {self.name}", os.sep)
+        return lambda x: x + 1
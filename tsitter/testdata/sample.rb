@@ -0,0 +1,17 @@
+require "set"
+
+# greets people
+class Greeter < Base
+  attr_reader :name
+
+  def initialize(name = "world", count: 1)
+    @name = name
+    @@count = 0.5
+    $items = [1, 2, nil, true]
+  end
+
+  def greet(*args)
+    puts "hello #{@name}" if @name != "" && !false
+    args.map { |x| x + 1 }
+  end
+end
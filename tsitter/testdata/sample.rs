@@ -0,0 +1,19 @@
+use std::fmt;
+
+// greets people
+pub struct Greeter<'a> {
+    name: &'a str,
+    count: u32,
+}
+
+impl<'a> Greeter<'a> {
+    /// Greets
+    pub fn greet(&self, ratio: f64) -> bool {
+        let c = 'c';
+        let items = vec![1, 2, 3];
+        if self.count > 0 && !false {
+            println!("hello {}", self.name);
+        }
+        true
+    }
+}
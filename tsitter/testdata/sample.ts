@@ -0,0 +1,21 @@
+import { readFile } from "fs";
+
+// greets people
+interface Named {
+  name: string;
+}
+
+export class Greeter<T> implements Named {
+  private items: number[] = [1, 2.5];
+
+  constructor(public name: string = "world") {}
+
+  greet(prefix?: string): boolean {
+    if (this.name !== "" && !false) {
+      console.log(`hello ${this.name}`);
+    }
+    return true;
+  }
+}
+
+type Id = number | null;
@@ -1,6 +1,8 @@
 package tsitter
 
 import (
+	"context"
+
 	sitter "github.com/smacker/go-tree-sitter"
 	"github.com/smacker/gum"
 )
@@ -25,39 +27,77 @@ var goTokenTypes = map[string]bool{
 	"func_literal":               true,
 }
 
-// Get all symbols for a language
+// Parse parses the content and converts it to gum.Tree
 //
-// gl := golang.GetLanguage()
-// for i := uint32(0); i < gl.SymbolCount(); i++ {
-// 	if gl.SymbolType(sitter.Symbol(i)) != sitter.SymbolTypeRegular {
-// 		continue
-// 	}
+// tree-sitter recovers from syntax errors, the result contains ERROR nodes in such case.
+func Parse(content []byte, lang *Language) (*gum.Tree, error) {
+	n, err := sitter.ParseCtx(context.Background(), content, lang.Grammar())
+	if err != nil {
+		return nil, err
+	}
 
-// 	fmt.Println(gl.SymbolName(sitter.Symbol(i)))
-// }
+	return lang.ToTree(n, content), nil
+}
 
-// ToTree converts bblfsh.Node to gum.Tree
+// ToTree converts sitter.Node of Go source to gum.Tree
 func ToTree(n *sitter.Node, source []byte) *gum.Tree {
-	t := toTree(n, source)
+	return GetLanguage("go").ToTree(n, source)
+}
+
+// ToTree converts sitter.Node to gum.Tree
+func (l *Language) ToTree(n *sitter.Node, source []byte) *gum.Tree {
+	t := l.toTree(n, source)
 	t.Refresh()
 	return t
 }
 
-func toTree(n *sitter.Node, source []byte) *gum.Tree {
+func (l *Language) toTree(n *sitter.Node, source []byte) *gum.Tree {
 	var children []*gum.Tree
-	for i := uint32(0); i < n.NamedChildCount(); i++ {
-		children = append(children, toTree(n.NamedChild(int(i)), source))
-	}
-	var value string
-	if _, ok := goTokenTypes[n.Type()]; ok {
-		value = string(source[n.StartByte():n.EndByte()])
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		if !c.IsNamed() {
+			continue
+		}
+		child := l.toTree(c, source)
+		// the role of the child in the parent, for example "condition"
+		if field := n.FieldNameForChild(i); field != "" {
+			child.SetAttr("field", field)
+		}
+		children = append(children, child)
 	}
 	tree := &gum.Tree{
 		Type:     n.Type(),
 		Meta:     n,
-		Value:    value,
+		Value:    l.label(n, source),
 		Children: children,
+		Pos:      int(n.StartByte()),
+		Length:   int(n.EndByte() - n.StartByte()),
 	}
 
 	return tree
 }
+
+func (l *Language) label(n *sitter.Node, source []byte) string {
+	if force, ok := l.TokenTypes[n.Type()]; ok {
+		if force {
+			return n.Content(source)
+		}
+		return ""
+	}
+	if !l.autoLabels {
+		return ""
+	}
+
+	if n.NamedChildCount() == 0 {
+		return n.Content(source)
+	}
+	// operators are anonymous nodes, use them as labels of expressions
+	for i := 0; i < int(n.ChildCount()); i++ {
+		field := n.FieldNameForChild(i)
+		if c := n.Child(i); !c.IsNamed() && (field == "operator" || field == "operators") {
+			return c.Content(source)
+		}
+	}
+
+	return ""
+}
@@ -25,6 +25,12 @@ func TestToTree(t *testing.T) {
 	withLabel := src.Children[0].Children[0]
 	assert.Equal("package_identifier", withLabel.Type)
 	assert.Equal("main", withLabel.Value)
+	assert.Equal("main", string(b[withLabel.Pos:withLabel.End()]))
+	_, hasField := withLabel.GetAttr("field")
+	assert.False(hasField)
+	fn := src.Children[len(src.Children)-1]
+	field, _ := fn.Children[0].GetAttr("field")
+	assert.Equal("name", field)
 
 	b, err = ioutil.ReadFile("testdata/dst.go")
 	assert.NoError(err)
@@ -36,7 +42,7 @@ func TestToTree(t *testing.T) {
 
 	// check that mapping works
 	mappings := gum.Match(src, dst)
-	assert.Len(mappings, 16)
+	assert.Len(mappings, 17)
 	for _, m := range mappings {
 		fmt.Println(m)
 	}
@@ -1,21 +1,65 @@
 package uast
 
 import (
+	"bytes"
+	"encoding/json"
 	"sort"
+	"strings"
 
 	"github.com/smacker/gum"
 	"gopkg.in/bblfsh/sdk.v2/uast"
 	"gopkg.in/bblfsh/sdk.v2/uast/nodes"
+	"gopkg.in/bblfsh/sdk.v2/uast/nodes/nodesproto"
+	uastyml "gopkg.in/bblfsh/sdk.v2/uast/yaml"
 )
 
+// Roles defines where roles of the nodes are kept in the tree
+type Roles int
+
+const (
+	// NoRoles drops roles of the nodes
+	NoRoles Roles = iota
+	// RolesInType appends roles to the type, for example "Identifier[Expression,Identifier]"
+	RolesInType
+	// RolesInLabel appends roles to the label
+	RolesInLabel
+	// RolesInAttrs keeps roles in the "roles" attribute, for example "Expression,Identifier"
+	RolesInAttrs
+)
+
+// binary format of nodes starts with the magic number
+var binaryMagic = []byte("\x00bgr")
+
+// Unmarshal decodes nodes.Node exported from bblfsh in binary, json or yaml format
+func Unmarshal(data []byte) (nodes.Node, error) {
+	if bytes.HasPrefix(data, binaryMagic) {
+		return nodesproto.ReadTree(bytes.NewReader(data))
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var v interface{}
+		// yaml in flow style also starts with a brace
+		if err := json.Unmarshal(data, &v); err == nil {
+			return nodes.ToNode(v, nil)
+		}
+	}
+
+	return uastyml.Unmarshal(data)
+}
+
 // ToTree converts bblfsh.Node to gum.Tree
 func ToTree(n nodes.Node) *gum.Tree {
-	t := toTree(n)
+	return ToTreeWithRoles(n, NoRoles)
+}
+
+// ToTreeWithRoles converts bblfsh.Node to gum.Tree keeping roles of the nodes
+func ToTreeWithRoles(n nodes.Node, roles Roles) *gum.Tree {
+	t := toTree(n, roles)
 	t.Refresh()
 	return t
 }
 
-func toTree(n nodes.Node) *gum.Tree {
+func toTree(n nodes.Node, roles Roles) *gum.Tree {
 	children := getChildren(n)
 	tree := &gum.Tree{
 		Type:     uast.TypeOf(n),
@@ -23,8 +67,23 @@ func toTree(n nodes.Node) *gum.Tree {
 		Children: make([]*gum.Tree, len(children)),
 		Meta:     n,
 	}
+	switch roles {
+	case RolesInType:
+		tree.Type += rolesString(n)
+	case RolesInLabel:
+		tree.Value += rolesString(n)
+	case RolesInAttrs:
+		if names := roleNames(n); len(names) > 0 {
+			tree.SetAttr("roles", strings.Join(names, ","))
+		}
+	}
+	pos := uast.PositionsOf(n)
+	if start, end := pos.Start(), pos.End(); start != nil && end != nil {
+		tree.Pos = int(start.Offset)
+		tree.Length = int(end.Offset - start.Offset)
+	}
 	for i, child := range children {
-		tree.Children[i] = toTree(child)
+		tree.Children[i] = toTree(child, roles)
 	}
 
 	return tree
@@ -67,3 +126,20 @@ func getChildren(n nodes.Node) []nodes.Node {
 
 	return filtered
 }
+
+func rolesString(n nodes.Node) string {
+	names := roleNames(n)
+	if len(names) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+func roleNames(n nodes.Node) []string {
+	roles := uast.RolesOf(n)
+	names := make([]string, len(roles))
+	for i, r := range roles {
+		names[i] = r.String()
+	}
+	return names
+}
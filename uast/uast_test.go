@@ -1,6 +1,8 @@
 package uast
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"strings"
@@ -8,6 +10,8 @@ import (
 
 	"github.com/smacker/gum"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/bblfsh/sdk.v2/uast/nodes/nodesproto"
 	uastyml "gopkg.in/bblfsh/sdk.v2/uast/yaml"
 )
 
@@ -55,3 +59,53 @@ func treePrint(t *gum.Tree, tab int) {
 		treePrint(c, tab+1)
 	}
 }
+
+func TestUnmarshalFormats(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/src.uast")
+	require.NoError(t, err)
+	node, err := Unmarshal(b)
+	require.NoError(t, err)
+	expected := ToTree(node)
+
+	jsonb, err := json.Marshal(node)
+	require.NoError(t, err)
+	var bin bytes.Buffer
+	require.NoError(t, nodesproto.WriteTo(&bin, node))
+
+	for name, data := range map[string][]byte{"json": jsonb, "binary": bin.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			n, err := Unmarshal(data)
+			require.NoError(t, err)
+			tree := ToTree(n)
+			assert.True(t, expected.IsIsomorphicTo(tree))
+			assert.Equal(t, expected.Length, tree.Length)
+		})
+	}
+
+	_, err = Unmarshal([]byte("{ broken"))
+	assert.Error(t, err)
+}
+
+func TestToTreeWithRoles(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/src.uast")
+	require.NoError(t, err)
+	node, err := Unmarshal(b)
+	require.NoError(t, err)
+
+	tree := ToTreeWithRoles(node, RolesInType)
+	assert.Equal(t, "CompilationUnit[File]", tree.Type)
+	modifier := tree.Children[0].Children[1]
+	assert.Equal(t, "Modifier[Visibility,World]", modifier.Type)
+	assert.Equal(t, "public", modifier.Value)
+
+	tree = ToTreeWithRoles(node, RolesInLabel)
+	modifier = tree.Children[0].Children[1]
+	assert.Equal(t, "Modifier", modifier.Type)
+	assert.Equal(t, "public[Visibility,World]", modifier.Value)
+
+	tree = ToTreeWithRoles(node, RolesInAttrs)
+	modifier = tree.Children[0].Children[1]
+	assert.Equal(t, "Modifier", modifier.Type)
+	assert.Equal(t, "public", modifier.Value)
+	assert.Equal(t, []gum.Attr{{Key: "roles", Value: "Visibility,World"}}, modifier.Attrs)
+}
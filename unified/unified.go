@@ -0,0 +1,376 @@
+// Package unified renders actions of the trees as a textual diff in unified format
+//
+// A line is changed only if it contains a changed node, so hunks follow the changes of the trees
+// instead of the differences of the text. Headers of hunks with moved nodes refer to the line
+// on the other side, for example "@@ -3,5 +2,0 @@ moved to line 12".
+//
+// AlignLines and Hunks are exported for other renderers of the same diff.
+package unified
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/smacker/gum"
+)
+
+// File is a version of the file to compare
+type File struct {
+	// Name is written in the header of the diff, for example "a/main.go"
+	Name    string
+	Content []byte
+	// Tree of the content, positions of the nodes are required
+	Tree *gum.Tree
+}
+
+// Write writes the difference between src and dst made by the actions in unified format
+// with the number of context lines around changes
+//
+// Nothing is written if the actions don't change any line.
+func Write(w io.Writer, src, dst *File, mappings []gum.Mapping, actions []*gum.Action, context int) error {
+	srcChanges, dstChanges := gum.Changes(actions, mappings)
+	srcFile := newFile(src, srcChanges)
+	dstFile := newFile(dst, dstChanges)
+
+	for _, m := range mappings {
+		if srcChanges[m[0]] != gum.Moved || m[0].Length == 0 || m[1].Length == 0 {
+			continue
+		}
+		if hasMovedAncestor(m[0], srcChanges) {
+			continue
+		}
+		srcLine, dstLine := LineOf(srcFile.lines, m[0].Pos), LineOf(dstFile.lines, m[1].Pos)
+		srcFile.annotate(srcLine, fmt.Sprintf("moved to line %d", dstLine+1))
+		dstFile.annotate(dstLine, fmt.Sprintf("moved from line %d", srcLine+1))
+	}
+
+	hunks := Hunks(AlignLines(srcFile.toLines(), dstFile.toLines()), context)
+	if len(hunks) == 0 {
+		return nil
+	}
+	for _, h := range hunks {
+		for _, op := range h.Ops {
+			switch op.Op {
+			case Delete:
+				h.Annotations = append(h.Annotations, srcFile.annotations[op.Src]...)
+			case Insert:
+				h.Annotations = append(h.Annotations, dstFile.annotations[op.Dst]...)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- %s\n+++ %s\n", src.Name, dst.Name)
+	for _, h := range hunks {
+		writeHunk(bw, h, srcFile, dstFile)
+	}
+
+	return bw.Flush()
+}
+
+// only the outermost moved node is annotated
+func hasMovedAncestor(t *gum.Tree, changes map[*gum.Tree]gum.Change) bool {
+	for p := t.GetParent(); p != nil; p = p.GetParent() {
+		if changes[p] == gum.Moved {
+			return true
+		}
+	}
+	return false
+}
+
+// file is the content split into lines with lines containing changed nodes
+type file struct {
+	content []byte
+	// offsets of the beginnings of the lines
+	lines       []int
+	changed     []bool
+	annotations map[int][]string
+}
+
+func newFile(f *File, changes map[*gum.Tree]gum.Change) *file {
+	res := &file{
+		content:     f.Content,
+		lines:       LineStarts(f.Content),
+		annotations: make(map[int][]string),
+	}
+
+	// inner nodes go after outer ones in pre-order and override them
+	marked := make([]bool, len(f.Content))
+	fill := func(t *gum.Tree, v bool) {
+		for i := t.Pos; i < t.End() && i < len(marked); i++ {
+			marked[i] = v
+		}
+	}
+	for _, t := range gum.PreOrder(f.Tree) {
+		c := changes[t]
+		if c == gum.Unchanged || t.Length == 0 {
+			continue
+		}
+		fill(t, true)
+
+		// the whole subtree is moved, other changes are made to the node itself
+		if c != gum.Moved {
+			for _, child := range t.Children {
+				fill(child, false)
+			}
+		}
+	}
+
+	res.changed = make([]bool, len(res.lines))
+	for i := range res.lines {
+		start, end := res.line(i)
+		for j := start; j < end; j++ {
+			if marked[j] && !isSpace(f.Content[j]) {
+				res.changed[i] = true
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+// line returns the range of the line including the new line character
+func (f *file) line(i int) (int, int) {
+	if i+1 < len(f.lines) {
+		return f.lines[i], f.lines[i+1]
+	}
+	return f.lines[i], len(f.content)
+}
+
+func (f *file) annotate(line int, s string) {
+	f.annotations[line] = append(f.annotations[line], s)
+}
+
+func (f *file) toLines() Lines {
+	text := make([]string, len(f.lines))
+	for i := range f.lines {
+		// the line without the new line at the end of the file is different
+		start, end := f.line(i)
+		text[i] = string(f.content[start:end])
+	}
+	return Lines{Text: text, Changed: f.changed}
+}
+
+// LineStarts returns offsets of the beginnings of the lines of the content
+func LineStarts(content []byte) []int {
+	if len(content) == 0 {
+		return nil
+	}
+	starts := []int{0}
+	for i, ch := range content {
+		if ch == '\n' && i+1 < len(content) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// LineOf returns 0-based number of the line containing the offset
+func LineOf(starts []int, offset int) int {
+	return sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+}
+
+// Op is the kind of a line of the diff
+type Op int8
+
+const (
+	// Equal is an unchanged line of both files
+	Equal Op = iota
+	// Delete is a line of the source file only
+	Delete
+	// Insert is a line of the destination file only
+	Insert
+)
+
+// LineOp is a line of the diff, Src and Dst are 0-based numbers of the line in the files
+// or -1 if the line is missing in the file
+type LineOp struct {
+	Op  Op
+	Src int
+	Dst int
+}
+
+// Lines of a file to align
+type Lines struct {
+	// Text of every line, unchanged lines with the same text are aligned
+	Text []string
+	// Changed lines are never aligned
+	Changed []bool
+}
+
+// AlignLines matches unchanged lines with the same text, changed lines are never matched
+func AlignLines(src, dst Lines) []LineOp {
+	tokens := make(map[string]rune)
+	var changed int
+	toRunes := func(l Lines) []rune {
+		rs := make([]rune, len(l.Text))
+		for i, text := range l.Text {
+			key := "=" + text
+			if l.Changed[i] {
+				// unique key
+				changed++
+				key = fmt.Sprintf("!%d", changed)
+			}
+			r, ok := tokens[key]
+			if !ok {
+				r = tokenRune(len(tokens))
+				tokens[key] = r
+			}
+			rs[i] = r
+		}
+		return rs
+	}
+	srcRunes, dstRunes := toRunes(src), toRunes(dst)
+
+	dmp := diffmatchpatch.New()
+	var ops []LineOp
+	var i, j int
+	for _, d := range dmp.DiffMainRunes(srcRunes, dstRunes, false) {
+		for range []rune(d.Text) {
+			switch d.Type {
+			case diffmatchpatch.DiffEqual:
+				ops = append(ops, LineOp{Op: Equal, Src: i, Dst: j})
+				i++
+				j++
+			case diffmatchpatch.DiffDelete:
+				ops = append(ops, LineOp{Op: Delete, Src: i, Dst: -1})
+				i++
+			case diffmatchpatch.DiffInsert:
+				ops = append(ops, LineOp{Op: Insert, Src: -1, Dst: j})
+				j++
+			}
+		}
+	}
+
+	return ops
+}
+
+// tokenRune converts index to a rune skipping surrogates that can't be encoded
+func tokenRune(i int) rune {
+	if i >= 0xD800 {
+		i += 0x800
+	}
+	return rune(i + 1)
+}
+
+// Hunk is a range of line operations with changes and context around them
+type Hunk struct {
+	Ops []LineOp
+	// Annotations are written in the header after the ranges
+	Annotations []string
+	// lines of both files before the hunk
+	srcBefore, dstBefore int
+}
+
+// Hunks groups changes with the number of context lines around them into hunks
+//
+// Hunks with overlapping context are merged, so every hunk keeps the full context
+// except at the beginning and the end of the file as patch tools expect.
+func Hunks(ops []LineOp, context int) []*Hunk {
+	var hunks []*Hunk
+	add := func(start, end int) {
+		h := &Hunk{Ops: ops[start:end]}
+		for _, op := range ops[:start] {
+			if op.Src >= 0 {
+				h.srcBefore++
+			}
+			if op.Dst >= 0 {
+				h.dstBefore++
+			}
+		}
+		hunks = append(hunks, h)
+	}
+
+	start, end := -1, -1
+	for i, op := range ops {
+		if op.Op == Equal {
+			continue
+		}
+		if start >= 0 && i-context <= end {
+			end = minInt(i+context+1, len(ops))
+			continue
+		}
+		if start >= 0 {
+			add(start, end)
+		}
+		start, end = maxInt(i-context, 0), minInt(i+context+1, len(ops))
+	}
+	if start >= 0 {
+		add(start, end)
+	}
+
+	return hunks
+}
+
+// Header in unified diff format: @@ -start,count +start,count @@ annotations
+func (h *Hunk) Header() string {
+	var srcCount, dstCount int
+	for _, op := range h.Ops {
+		if op.Src >= 0 {
+			srcCount++
+		}
+		if op.Dst >= 0 {
+			dstCount++
+		}
+	}
+	// empty range starts at the line before it
+	srcStart, dstStart := h.srcBefore, h.dstBefore
+	if srcCount > 0 {
+		srcStart++
+	}
+	if dstCount > 0 {
+		dstStart++
+	}
+
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", srcStart, srcCount, dstStart, dstCount)
+	if len(h.Annotations) > 0 {
+		header += " " + strings.Join(h.Annotations, ", ")
+	}
+	return header
+}
+
+func writeHunk(w io.Writer, h *Hunk, src, dst *file) {
+	fmt.Fprintln(w, h.Header())
+	for _, op := range h.Ops {
+		switch op.Op {
+		case Equal:
+			writeLine(w, " ", src, op.Src)
+		case Delete:
+			writeLine(w, "-", src, op.Src)
+		case Insert:
+			writeLine(w, "+", dst, op.Dst)
+		}
+	}
+}
+
+func writeLine(w io.Writer, prefix string, f *file, i int) {
+	start, end := f.line(i)
+	text := string(f.content[start:end])
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n\\ No newline at end of file\n"
+	}
+	io.WriteString(w, prefix+text)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,282 @@
+package unified
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/smacker/gum/golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diff(t *testing.T, src, dst string, context int) string {
+	parse := func(name, content string) *File {
+		f, err := parser.ParseFile(token.NewFileSet(), name, content, parser.ParseComments)
+		require.NoError(t, err)
+		return &File{Name: name, Content: []byte(content), Tree: golang.ToTree(f)}
+	}
+	srcFile, dstFile := parse("a/main.go", src), parse("b/main.go", dst)
+
+	mappings := gum.Match(srcFile.Tree, dstFile.Tree)
+	actions := gum.Patch(srcFile.Tree, dstFile.Tree, mappings)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, srcFile, dstFile, mappings, actions, context))
+
+	patched, err := apply(src, buf.String())
+	require.NoError(t, err)
+	assert.Equal(t, dst, patched, "the diff must transform src to dst")
+	gitApply(t, src, dst, buf.String())
+
+	return buf.String()
+}
+
+// gitApply checks that git accepts the diff and it transforms src to dst
+func gitApply(t *testing.T, src, dst, diff string) {
+	if diff == "" {
+		return
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Log("git is not installed")
+		return
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.diff"), []byte(diff), 0644))
+
+	cmd := exec.Command("git", "apply", "main.diff")
+	cmd.Dir = dir
+	// apply outside of any repository
+	cmd.Env = append(os.Environ(), "GIT_CEILING_DIRECTORIES="+filepath.Dir(dir))
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	patched, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, dst, string(patched), "git apply must transform src to dst")
+}
+
+// apply applies the diff to the text checking context and deleted lines
+func apply(text, diff string) (string, error) {
+	if diff == "" {
+		return text, nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var result []string
+	var pos int
+	difflines := strings.SplitAfter(diff, "\n")
+	for i := 2; i < len(difflines) && difflines[i] != ""; i++ {
+		l := difflines[i]
+		if strings.HasPrefix(l, "@@") {
+			var srcStart, srcCount, dstStart, dstCount int
+			if _, err := fmt.Sscanf(l, "@@ -%d,%d +%d,%d @@", &srcStart, &srcCount, &dstStart, &dstCount); err != nil {
+				return "", err
+			}
+			if srcCount > 0 {
+				srcStart--
+			}
+			if srcStart < pos {
+				return "", fmt.Errorf("overlapping hunk %s", l)
+			}
+			result = append(result, lines[pos:srcStart]...)
+			pos = srcStart
+			continue
+		}
+
+		line := l[1:]
+		if i+1 < len(difflines) && strings.HasPrefix(difflines[i+1], "\\") {
+			line = strings.TrimSuffix(line, "\n")
+			i++
+		}
+		switch l[0] {
+		case ' ', '-':
+			if pos >= len(lines) || lines[pos] != line {
+				return "", fmt.Errorf("line %d doesn't match %q", pos+1, line)
+			}
+			pos++
+			if l[0] == ' ' {
+				result = append(result, line)
+			}
+		case '+':
+			result = append(result, line)
+		}
+	}
+	result = append(result, lines[pos:]...)
+
+	return strings.Join(result, ""), nil
+}
+
+const src = `package main
+
+// Max returns the largest number
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func main() {
+	println(Max(1, 2))
+	println(Min(1, 2))
+}
+`
+
+func TestWriteMove(t *testing.T) {
+	dst := `package main
+
+func Min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func main() {
+	println(Max(1, 2))
+	println(Min(1, 2))
+}
+
+// Max returns the largest number
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+`
+	assert.Equal(t, `--- a/main.go
++++ b/main.go
+@@ -2,10 +2,2 @@ moved to line 15
+ 
+-// Max returns the largest number
+-func Max(a, b int) int {
+-	if a > b {
+-		return a
+-	}
+-	return b
+-}
+-
+ func Min(a, b int) int {
+@@ -21,1 +13,9 @@ moved from line 3
+ }
++
++// Max returns the largest number
++func Max(a, b int) int {
++	if a > b {
++		return a
++	}
++	return b
++}
+`, diff(t, src, dst, 1))
+
+	// hunks with overlapping context are merged keeping annotations of both
+	out := diff(t, src, dst, 10)
+	assert.Equal(t, 1, strings.Count(out, "@@ -"))
+	assert.Contains(t, out, "@@ moved to line 15, moved from line 3\n")
+}
+
+func TestWriteUpdate(t *testing.T) {
+	dst := `package main
+
+// Max returns the largest number
+func Max(a, b int) int {
+	if a >= b {
+		return a
+	}
+	return b
+}
+
+func Min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func main() {
+	println(Max(1, 2))
+	println(Min(1, 3))
+}
+`
+	assert.Equal(t, `--- a/main.go
++++ b/main.go
+@@ -2,7 +2,7 @@
+ 
+ // Max returns the largest number
+ func Max(a, b int) int {
+-	if a > b {
++	if a >= b {
+ 		return a
+ 	}
+ 	return b
+@@ -17,5 +17,5 @@
+ 
+ func main() {
+ 	println(Max(1, 2))
+-	println(Min(1, 2))
++	println(Min(1, 3))
+ }
+`, diff(t, src, dst, 3))
+
+	// close changes are in the same hunk
+	out := diff(t, src, dst, 7)
+	assert.Equal(t, 1, strings.Count(out, "@@ -"))
+}
+
+func TestWriteInsertAtEnd(t *testing.T) {
+	dst := strings.TrimSuffix(src, "}\n") + "\tprintln(0)\n}"
+	assert.Equal(t, `--- a/main.go
++++ b/main.go
+@@ -20,2 +20,3 @@
+ 	println(Min(1, 2))
+-}
++	println(0)
++}
+\ No newline at end of file
+`, diff(t, src, dst, 1))
+}
+
+func TestWriteNoChanges(t *testing.T) {
+	assert.Empty(t, diff(t, src, src, 3))
+}
+
+func TestHunks(t *testing.T) {
+	var ops []LineOp
+	for i := 0; i < 20; i++ {
+		ops = append(ops, LineOp{Op: Equal, Src: i, Dst: i})
+	}
+	ops[2].Op = Delete
+	ops[5].Op = Delete
+	ops[15].Op = Insert
+
+	hunks := Hunks(ops, 2)
+	require.Len(t, hunks, 2)
+	assert.Equal(t, ops[0:8], hunks[0].Ops)
+	assert.Equal(t, ops[13:18], hunks[1].Ops)
+	assert.Equal(t, "@@ -14,5 +14,5 @@", hunks[1].Header())
+
+	// touching context is merged
+	assert.Len(t, Hunks(ops, 5), 1)
+	assert.Len(t, Hunks(ops, 0), 3)
+}
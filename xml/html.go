@@ -0,0 +1,134 @@
+package xml
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/smacker/gum"
+	"golang.org/x/net/html"
+)
+
+// ParseHTML converts HTML document to gum.Tree
+//
+// The parser is tolerant to errors in the markup like browsers are:
+// void elements never have children, missing end tags are implied
+// and end tags without open elements are ignored.
+// Unlike a browser it doesn't add missing html, head and body elements.
+func ParseHTML(content []byte) (*gum.Tree, error) {
+	z := html.NewTokenizer(bytes.NewReader(content))
+	root := &gum.Tree{Type: DocumentType, Length: len(content)}
+	stack := []*gum.Tree{root}
+	offset := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				break
+			}
+			return nil, z.Err()
+		}
+		raw := z.Raw()
+		start, end := offset, offset+len(raw)
+		offset = end
+		tok := z.Token()
+
+		var t *gum.Tree
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			for len(stack) > 1 && impliesEnd(stack[len(stack)-1].Value, tok.Data) {
+				stack = closeElement(stack)
+			}
+
+			t = newElement(tok.Data, raw, start)
+			names := make([]string, len(tok.Attr))
+			values := make([]string, len(tok.Attr))
+			for i, a := range tok.Attr {
+				names[i], values[i] = a.Key, a.Val
+				if a.Namespace != "" {
+					names[i] = a.Namespace + ":" + a.Key
+				}
+			}
+			addAttributes(t, names, values, raw, start)
+		case html.EndTagToken:
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].Value != tok.Data {
+					continue
+				}
+				for len(stack) > i+1 {
+					stack = closeElement(stack)
+				}
+				stack[i].Length = end - stack[i].Pos
+				stack = stack[:i]
+				break
+			}
+		case html.TextToken:
+			t = newText(TextType, tok.Data, raw, start)
+		case html.CommentToken:
+			t = newText(CommentType, tok.Data, raw, start)
+		case html.DoctypeToken:
+			t = &gum.Tree{Type: DirectiveType, Value: "DOCTYPE " + tok.Data, Pos: start, Length: end - start}
+		}
+
+		if t == nil {
+			continue
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, t)
+		if tt == html.StartTagToken && !voidElements[tok.Data] {
+			stack = append(stack, t)
+		}
+	}
+
+	for len(stack) > 1 {
+		stack = closeElement(stack)
+	}
+
+	root.Refresh()
+	return root, nil
+}
+
+// closeElement removes the last element without the end tag from the stack,
+// it ends with its last child
+func closeElement(stack []*gum.Tree) []*gum.Tree {
+	t := stack[len(stack)-1]
+	if len(t.Children) > 0 {
+		if end := t.Children[len(t.Children)-1].End(); end > t.End() {
+			t.Length = end - t.Pos
+		}
+	}
+	return stack[:len(stack)-1]
+}
+
+// elements that can't have content
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// elements that close an open p element
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true, "div": true,
+	"dl": true, "fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true,
+}
+
+// impliesEnd returns true if the start tag of next element ends the open element
+func impliesEnd(open, next string) bool {
+	switch open {
+	case "p":
+		return blockElements[next]
+	case "li":
+		return next == "li"
+	case "dt", "dd":
+		return next == "dt" || next == "dd"
+	case "td", "th":
+		return next == "td" || next == "th" || next == "tr"
+	case "tr":
+		return next == "tr"
+	case "option":
+		return next == "option" || next == "optgroup"
+	default:
+		return false
+	}
+}
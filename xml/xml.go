@@ -0,0 +1,213 @@
+// Package xml converts XML and HTML documents into gum.Tree
+//
+// Elements are nodes of type Element with the name as the label,
+// their children are attributes followed by the content.
+// Attributes have the name as the label and the value as the only child.
+// They are nodes rather than gum.Tree Attrs to keep positions of the values,
+// so changed values are updated and highlighted on their own.
+// Text without anything but whitespace is skipped, labels of other text nodes are trimmed.
+//
+// Attributes of elements are sorted by the name,
+// reordering of them isn't a change of the document and doesn't produce moves.
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/smacker/gum"
+)
+
+// Types of the nodes
+const (
+	DocumentType       = "Document"
+	ElementType        = "Element"
+	AttributeType      = "Attribute"
+	AttributeValueType = "AttributeValue"
+	TextType           = "Text"
+	CommentType        = "Comment"
+	ProcInstType       = "ProcInst"
+	DirectiveType      = "Directive"
+)
+
+// Parse converts XML document to gum.Tree
+func Parse(content []byte) (*gum.Tree, error) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	root := &gum.Tree{Type: DocumentType, Length: len(content)}
+	stack := []*gum.Tree{root}
+	var names []xml.Name
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := int(dec.InputOffset())
+
+		parent := stack[len(stack)-1]
+		var t *gum.Tree
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			t = newElement(qualifiedName(tok.Name), content[start:end], start)
+			attrs := make([]string, len(tok.Attr))
+			values := make([]string, len(tok.Attr))
+			for i, a := range tok.Attr {
+				attrs[i], values[i] = qualifiedName(a.Name), a.Value
+			}
+			addAttributes(t, attrs, values, content[start:end], start)
+			stack = append(stack, t)
+			names = append(names, tok.Name)
+		case xml.EndElement:
+			if len(names) == 0 || names[len(names)-1] != tok.Name {
+				return nil, fmt.Errorf("unexpected end element </%s> at %d", qualifiedName(tok.Name), start)
+			}
+			parent.Length = end - parent.Pos
+			stack = stack[:len(stack)-1]
+			names = names[:len(names)-1]
+			continue
+		case xml.CharData:
+			t = newText(TextType, string(tok), content[start:end], start)
+		case xml.Comment:
+			t = newText(CommentType, string(tok), content[start:end], start)
+		case xml.ProcInst:
+			t = &gum.Tree{Type: ProcInstType, Value: strings.TrimSpace(tok.Target + " " + string(tok.Inst)), Pos: start, Length: end - start}
+		case xml.Directive:
+			t = &gum.Tree{Type: DirectiveType, Value: strings.TrimSpace(string(tok)), Pos: start, Length: end - start}
+		}
+
+		if t != nil {
+			parent.Children = append(parent.Children, t)
+		}
+	}
+	if len(names) > 0 {
+		return nil, fmt.Errorf("element <%s> is not closed", qualifiedName(names[len(names)-1]))
+	}
+
+	root.Refresh()
+	return root, nil
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func newElement(name string, raw []byte, pos int) *gum.Tree {
+	return &gum.Tree{Type: ElementType, Value: name, Pos: pos, Length: len(raw)}
+}
+
+// addAttributes adds attributes to the element sorted by name,
+// raw is the start tag of the element at pos
+func addAttributes(t *gum.Tree, names, values []string, raw []byte, pos int) {
+	spans := scanAttributes(raw)
+	var attrs []*gum.Tree
+	for i, name := range names {
+		a := &gum.Tree{Type: AttributeType, Value: name}
+		v := &gum.Tree{Type: AttributeValueType, Value: values[i]}
+		if i < len(spans) {
+			s := spans[i]
+			a.Pos, a.Length = pos+s.start, s.end-s.start
+			v.Pos, v.Length = pos+s.valueStart, s.valueEnd-s.valueStart
+		}
+		a.Children = []*gum.Tree{v}
+		attrs = append(attrs, a)
+	}
+
+	sort.SliceStable(attrs, func(i, j int) bool { return attrs[i].Value < attrs[j].Value })
+	t.Children = append(t.Children, attrs...)
+}
+
+// attributeSpan is a position of an attribute in the start tag
+type attributeSpan struct {
+	start, end           int
+	valueStart, valueEnd int
+}
+
+// scanAttributes finds positions of the attributes in the raw start tag,
+// the parsers don't keep them
+func scanAttributes(raw []byte) []attributeSpan {
+	isEnd := func(i int) bool {
+		return i >= len(raw) || raw[i] == '>' || raw[i] == '/' && i+1 < len(raw) && raw[i+1] == '>'
+	}
+	isSpace := func(i int) bool {
+		return i < len(raw) && strings.IndexByte(" \t\r\n\f", raw[i]) >= 0
+	}
+
+	// skip the name of the element
+	i := 1
+	for !isEnd(i) && !isSpace(i) {
+		i++
+	}
+
+	var spans []attributeSpan
+	for {
+		for isSpace(i) || i < len(raw) && raw[i] == '/' && !isEnd(i) {
+			i++
+		}
+		if isEnd(i) {
+			return spans
+		}
+
+		s := attributeSpan{start: i}
+		for !isEnd(i) && !isSpace(i) && raw[i] != '=' {
+			i++
+		}
+		s.end, s.valueStart, s.valueEnd = i, i, i
+
+		j := i
+		for isSpace(j) {
+			j++
+		}
+		if j < len(raw) && raw[j] == '=' {
+			j++
+			for isSpace(j) {
+				j++
+			}
+			if j < len(raw) && (raw[j] == '"' || raw[j] == '\'') {
+				// the value is the content of the quotes
+				s.valueStart = j + 1
+				if k := bytes.IndexByte(raw[j+1:], raw[j]); k >= 0 {
+					j += k + 2
+				} else {
+					j = len(raw)
+				}
+				s.valueEnd = j - 1
+			} else {
+				s.valueStart = j
+				for !isSpace(j) && j < len(raw) && raw[j] != '>' {
+					j++
+				}
+				s.valueEnd = j
+			}
+			s.end = j
+			i = j
+		}
+
+		spans = append(spans, s)
+	}
+}
+
+// newText returns node for text or comment with trimmed label and position,
+// raw is the text in the source at pos
+//
+// Returns nil for whitespace.
+func newText(typ, value string, raw []byte, pos int) *gum.Tree {
+	value = strings.TrimSpace(value)
+	if value == "" && typ == TextType {
+		return nil
+	}
+
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	start := pos + len(raw) - len(trimmed)
+	trimmed = bytes.TrimRight(trimmed, " \t\r\n")
+	return &gum.Tree{Type: typ, Value: value, Pos: start, Length: len(trimmed)}
+}
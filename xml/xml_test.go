@@ -0,0 +1,125 @@
+package xml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/smacker/gum"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// text returns the source of the node
+func text(src string, t *gum.Tree) string {
+	return src[t.Pos:t.End()]
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `<?xml version="1.0"?>
+<!-- generated -->
+<svg xmlns:xlink="http://www.w3.org/1999/xlink" width='10' height="20">
+  <rect fill="red"/>
+  <text>a &amp; b</text>
+</svg>
+`
+	tree, err := Parse([]byte(src))
+	require.NoError(t, err)
+
+	assert.Equal(DocumentType, tree.Type)
+	require.Len(t, tree.Children, 3)
+	assert.Equal(ProcInstType, tree.Children[0].Type)
+	assert.Equal(`xml version="1.0"`, tree.Children[0].Value)
+	assert.Equal(CommentType, tree.Children[1].Type)
+	assert.Equal("generated", tree.Children[1].Value)
+
+	svg := tree.Children[2]
+	assert.Equal(ElementType, svg.Type)
+	assert.Equal("svg", svg.Value)
+	assert.Equal(src[strings.Index(src, "<svg"):strings.LastIndex(src, "\n")], text(src, svg))
+
+	// attributes are sorted by name and go before the content
+	var names []string
+	for _, c := range svg.Children[:3] {
+		assert.Equal(AttributeType, c.Type)
+		names = append(names, c.Value)
+	}
+	assert.Equal([]string{"height", "width", "xmlns:xlink"}, names)
+	width := svg.Children[1]
+	assert.Equal("width='10'", text(src, width))
+	assert.Equal("10", width.Children[0].Value)
+	assert.Equal("10", text(src, width.Children[0]))
+
+	rect := svg.Children[3]
+	assert.Equal(`<rect fill="red"/>`, text(src, rect))
+	assert.Equal(`fill="red"`, text(src, rect.Children[0]))
+
+	content := svg.Children[4].Children[0]
+	assert.Equal(TextType, content.Type)
+	assert.Equal("a & b", content.Value)
+	assert.Equal("a &amp; b", text(src, content))
+
+	_, err = Parse([]byte("<a><b></a>"))
+	assert.Error(err)
+	_, err = Parse([]byte("<a>"))
+	assert.Error(err)
+}
+
+func TestParseHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	src := `<!DOCTYPE html>
+<ul class=menu hidden>
+  <li>one
+  <li>two
+</ul>
+<p>text<div>a<br>b</div></span>
+<img src="logo.png">`
+	tree, err := ParseHTML([]byte(src))
+	require.NoError(t, err)
+
+	require.Len(t, tree.Children, 5)
+	assert.Equal(DirectiveType, tree.Children[0].Type)
+	assert.Equal("DOCTYPE html", tree.Children[0].Value)
+
+	ul := tree.Children[1]
+	assert.Equal("ul", ul.Value)
+	assert.Equal("<ul class=menu hidden>\n  <li>one\n  <li>two\n</ul>", text(src, ul))
+	assert.Equal("class=menu", text(src, ul.Children[0]))
+	assert.Equal("menu", text(src, ul.Children[0].Children[0]))
+	assert.Equal("hidden", ul.Children[1].Value)
+	assert.Equal("", ul.Children[1].Children[0].Value)
+
+	// li ends at the start of the next one
+	assert.Equal("<li>one", text(src, ul.Children[2]))
+	assert.Equal("one", ul.Children[2].Children[0].Value)
+	assert.Equal("<li>two", text(src, ul.Children[3]))
+
+	// p ends at the start of div, br is void
+	assert.Equal("<p>text", text(src, tree.Children[2]))
+	div := tree.Children[3]
+	assert.Equal("<div>a<br>b</div>", text(src, div))
+	require.Len(t, div.Children, 3)
+	assert.Equal("br", div.Children[1].Value)
+	assert.Empty(div.Children[1].Children)
+
+	img := tree.Children[4]
+	assert.Equal("img", img.Value)
+	assert.Equal("logo.png", img.Children[0].Children[0].Value)
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+
+	src, err := Parse([]byte(`<svg><rect width="10" fill="red"/><circle r="5"/></svg>`))
+	require.NoError(t, err)
+	dst, err := Parse([]byte(`<svg><rect fill="blue" width="10"/><circle r="5"/></svg>`))
+	require.NoError(t, err)
+
+	actions := gum.Patch(src, dst, gum.Match(src, dst))
+	require.Len(t, actions, 1)
+	assert.Equal(gum.Update, actions[0].Type)
+	assert.Equal("red", actions[0].Node.Value)
+	assert.Equal("blue", actions[0].Value)
+}